@@ -2,4 +2,8 @@ module mould
 
 go 1.19
 
-require github.com/dave/jennifer v1.6.1 // indirect
+require (
+	github.com/dave/jennifer v1.6.1
+	golang.org/x/crypto v0.14.0
+	golang.org/x/time v0.5.0
+)
@@ -3,14 +3,26 @@ package main
 import (
 	"fmt"
 	"bytes"
+	"encoding/json"
 	"strings"
+	"html"
 	"html/template"
 	"regexp"
+	"sort"
 	"path/filepath"
 	"flag"
 	"bufio"
+	"io"
+	"go/token"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+	"math"
 	. "github.com/dave/jennifer/jen"
 	"os"
+	"net/url"
+	"time"
+	"golang.org/x/crypto/bcrypt"
 )
 
 /*
@@ -24,10 +36,10 @@ type FormContent struct {
 }
 
 type FormAnswer struct {
-	Name string `json:"name"`
-	Address string `json:"address"`
-	StickerSheetAmount int `json:"sticker-sheet-amount"
-	AccessToken string `json:"access-token"`
+	Name string `form:"name" json:"name"`
+	Address string `form:"address" json:"address"`
+	StickerSheetAmount int `form:"sticker-sheet-amount" json:"sticker-sheet-amount"`
+	AccessToken string `form:"access-token" json:"access-token"`
 }
 
 
@@ -43,8 +55,14 @@ input[The rabbit boat but backwards]#access-token   = you know it.
 radio[Size]                                         = Small, Medium, Large
 */
 
-func jsonTag (value string) map[string]string {
-	return map[string]string{"json":value}
+// fieldTags returns the struct tags for a field: its json tag, where optional fields are omitted from the json when
+// left empty, and a form tag with the key it's posted under, for binding with e.g. gorilla/schema
+func fieldTags (key string, required bool) map[string]string {
+	value := key
+	if !required && value != "-" {
+		value += ",omitempty"
+	}
+	return map[string]string{"json":value, "form":key}
 }
 
 type genValue struct {
@@ -54,32 +72,567 @@ type genValue struct {
 	key string
 	required bool
 	options map[string]string
+	// the elements repeated by a repeat[...] block
+	children []genValue
+	// the checkbox option marked with a leading ^, which can't be checked together with the others
+	exclusive string
+	// per-field constraints from a {...} block following the title, e.g. `checkboxes[Top picks]{max=3}`
+	constraints map[string]string
+	// shown under the field, from a `help = ...` line following it or a {help=...} constraint
+	help string
+	// the line of the format file the element was declared on
+	line int
+	// the included file the element was declared in, empty for the format file itself
+	file string
+}
+
+// position describes where the element was declared, for error messages
+func (v genValue) position() string {
+	if v.file != "" {
+		return fmt.Sprintf("%s, line %d", v.file, v.line)
+	}
+	return fmt.Sprintf("line %d", v.line)
+}
+
+// answerField is a field of the generated FormAnswer struct
+type answerField struct {
+	key, title string
+	// the go type of the field: string, int, int64, float64, bool, []string, []byte, map[string][]string or time.Time
+	kind string
+	required bool
+	// set for answers that shouldn't be stored or shown, like passwords
+	sensitive bool
+	// the values a radio or select answer can take, for the json schema
+	enum []string
+	// the element the field answers, its title and the options it can be answered with, for the generated Fields
+	element, label string
+	options []string
+	// the fields of each entry of a repeat[...] block
+	entries []answerField
+}
+
+func (field answerField) typeCode() Code {
+	switch field.kind {
+	case "int":
+		return Int()
+	case "int64":
+		return Int64()
+	case "float64":
+		return Float64()
+	case "bool":
+		return Bool()
+	case "[]string":
+		return Index().String()
+	case "[]byte":
+		return Index().Byte()
+	case "map[string][]string":
+		return Map(String()).Index().String()
+	case "time.Time":
+		return Qual("time", "Time")
+	}
+	// a list of generated structs, e.g. []Attendee from a repeat[Attendee] block
+	if strings.HasPrefix(field.kind, "[]") {
+		return Index().Id(strings.TrimPrefix(field.kind, "[]"))
+	}
+	return String()
+}
+
+// csvCode generates the code formatting the field's value in answer as a csv cell
+func (field answerField) csvCode() Code {
+	value := Id("answer").Dot(field.title)
+	switch field.kind {
+	case "int":
+		return Qual("strconv", "Itoa").Call(value)
+	case "int64":
+		return Qual("strconv", "FormatInt").Call(value, Lit(10))
+	case "float64":
+		return Qual("strconv", "FormatFloat").Call(value, LitRune('f'), Lit(-1), Lit(64))
+	case "bool":
+		return Qual("strconv", "FormatBool").Call(value)
+	case "[]string":
+		return Qual("strings", "Join").Call(value, Lit("; "))
+	case "time.Time":
+		return Id("csvTime").Call(value)
+	case "string":
+		return value
+	}
+	// repeated entries and matrices don't fit in a single cell other than as json
+	return Id("csvJSON").Call(value)
+}
+
+// optionValues are the values that the options of a radio or select are posted as
+func optionValues(options []string, other bool) []string {
+	var values []string
+	for _, option := range options {
+		if option = strings.TrimSpace(option); option != "" {
+			values = append(values, strings.ToLower(option))
+		}
+	}
+	if other {
+		values = append(values, "other")
+	}
+	return values
+}
+
+// jsonSchema describes the json that answers are stored as, as a draft-07 json schema
+func jsonSchema(title string, fields []answerField) ([]byte, error) {
+	schema := fieldsSchema(fields)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	if title != "" {
+		schema["title"] = title
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// openAPI describes the routes of the generated form server as an openapi 3 spec, in yaml. the posted answers are
+// described with the same schema as --schema's stored answers, plus the csrf token
+func openAPI(title string, fields []answerField, multipart bool, server serverOptions) []byte {
+	if title == "" {
+		title = "form"
+	}
+	page := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"text/html": map[string]interface{}{}},
+		}
+	}
+	body := fieldsSchema(fields)
+	body["properties"].(map[string]interface{})["csrf_token"] = map[string]interface{}{
+		"type":        "string",
+		"description": "the token in the form, which has to match the csrf cookie set along with it",
+	}
+	required, _ := body["required"].([]string)
+	body["required"] = append([]string{"csrf_token"}, required...)
+	contentType := "application/x-www-form-urlencoded"
+	if multipart {
+		contentType = "multipart/form-data"
+	}
+	stored := map[string]interface{}{
+		"description": "the answer was stored, and the response page is next",
+		"headers": map[string]interface{}{
+			"Location": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		},
+	}
+	responses := map[string]interface{}{
+		"302": stored,
+		"400": page("a mistake in one of the answers, the form is shown again with what's wrong"),
+		"403": map[string]interface{}{"description": "the csrf token is missing, or the form has expired"},
+		"413": map[string]interface{}{"description": "the answer is too large"},
+	}
+	if server.redirectPath != "" {
+		delete(responses, "302")
+		responses["303"] = stored
+	}
+	if server.rateLimit > 0 {
+		responses["429"] = map[string]interface{}{"description": "too many answers from the same client"}
+	}
+	spec := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": title, "version": "1.0.0"},
+		"paths": map[string]interface{}{
+			"/": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":   "the form",
+					"responses": map[string]interface{}{"200": page("the form, along with a csrf cookie")},
+				},
+				"post": map[string]interface{}{
+					"summary": "submits an answer",
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content":  map[string]interface{}{contentType: map[string]interface{}{"schema": body}},
+					},
+					"responses": responses,
+				},
+			},
+		},
+	}
+	var b strings.Builder
+	writeYAML(&b, spec, 0)
+	return []byte(b.String())
+}
+
+// writeYAML writes v, made up of maps, lists and scalars, as yaml. keys are sorted, so that the same v is always
+// written the same way
+func writeYAML(b *strings.Builder, v interface{}, indent int) {
+	prefix := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			b.WriteString(prefix + yamlString(key) + ":")
+			writeYAMLValue(b, v[key], indent)
+		}
+	case []string:
+		for _, item := range v {
+			b.WriteString(prefix + "- " + yamlString(item) + "\n")
+		}
+	case []interface{}:
+		for _, item := range v {
+			b.WriteString(prefix + "-")
+			writeYAMLValue(b, item, indent)
+		}
+	}
+}
+
+// plainYAML matches the strings that yaml doesn't take for anything other than a string when written as-is
+var plainYAML = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_/. -]*$`)
+
+// yamlString writes text as a yaml string, quoted unless that's unnecessary
+func yamlString(text string) string {
+	switch strings.ToLower(text) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		return strconv.Quote(text)
+	}
+	if plainYAML.MatchString(text) && !strings.HasSuffix(text, " ") {
+		return text
+	}
+	return strconv.Quote(text)
+}
+
+// writeYAMLValue writes v after the key or dash it belongs to: scalars on the same line, anything else indented on
+// the lines after it
+func writeYAMLValue(b *strings.Builder, v interface{}, indent int) {
+	switch value := v.(type) {
+	case string:
+		b.WriteString(" " + yamlString(value) + "\n")
+	case map[string]interface{}:
+		if len(value) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAML(b, value, indent+2)
+	case []string:
+		if len(value) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAML(b, value, indent+2)
+	case []interface{}:
+		if len(value) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAML(b, value, indent+2)
+	default:
+		b.WriteString(" " + fmt.Sprint(value) + "\n")
+	}
+}
+
+// fieldsSchema is the json schema of an object with the given fields as its properties
+func fieldsSchema(fields []answerField) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	for _, field := range fields {
+		// the contents of uploaded files are saved separately, rather than in the json
+		if field.key == "-" {
+			continue
+		}
+		properties[field.key] = field.schema()
+		if field.required {
+			required = append(required, field.key)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schema is the json schema of the field's value
+func (field answerField) schema() map[string]interface{} {
+	switch field.kind {
+	case "int", "int64":
+		return map[string]interface{}{"type": "integer"}
+	case "float64":
+		return map[string]interface{}{"type": "number"}
+	case "bool":
+		return map[string]interface{}{"type": "boolean"}
+	case "[]string":
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	case "map[string][]string":
+		return map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}}
+	case "time.Time":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "string":
+		schema := map[string]interface{}{"type": "string"}
+		if len(field.enum) > 0 {
+			// optional fields left unanswered are omitted, rather than stored as ""
+			schema["enum"] = field.enum
+		}
+		return schema
+	}
+	// repeated entries
+	return map[string]interface{}{"type": "array", "items": fieldsSchema(field.entries)}
+}
+
+// schemaNode is the part of a json schema that --from-schema understands
+type schemaNode struct {
+	// usually a single type name, but can be a list of them, e.g. ["string", "null"]
+	Type json.RawMessage `json:"type"`
+	Title string `json:"title"`
+	Description string `json:"description"`
+	Format string `json:"format"`
+	Pattern string `json:"pattern"`
+	Enum []interface{} `json:"enum"`
+	Minimum *float64 `json:"minimum"`
+	Maximum *float64 `json:"maximum"`
+	MinLength *int `json:"minLength"`
+	MaxLength *int `json:"maxLength"`
+	Items *schemaNode `json:"items"`
+	Properties schemaProperties `json:"properties"`
+	Required []string `json:"required"`
+}
+
+// typeName is the node's type, the first one other than null if it has several
+func (node schemaNode) typeName() string {
+	var name string
+	if json.Unmarshal(node.Type, &name) == nil {
+		return name
+	}
+	var names []string
+	json.Unmarshal(node.Type, &names)
+	for _, name := range names {
+		if name != "null" {
+			return name
+		}
+	}
+	return ""
+}
+
+// schemaProperties are the properties of an object schema, in the order they were written in, which is the order
+// their fields end up in the form
+type schemaProperties []schemaProperty
+
+type schemaProperty struct {
+	name string
+	node schemaNode
+}
+
+func (props *schemaProperties) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// the opening {
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var node schemaNode
+		if err := dec.Decode(&node); err != nil {
+			return err
+		}
+		*props = append(*props, schemaProperty{name: token.(string), node: node})
+	}
+	return nil
+}
+
+// formatFromSchema writes a format file for a form answering the json schema in b, the inverse of --schema
+func formatFromSchema(b []byte) (string, error) {
+	var root schemaNode
+	if err := json.Unmarshal(b, &root); err != nil {
+		return "", fmt.Errorf("not a json schema: %w", err)
+	}
+	if root.typeName() != "object" || len(root.Properties) == 0 {
+		return "", fmt.Errorf("the schema has to describe an object with properties")
+	}
+	required := make(map[string]bool)
+	for _, name := range root.Required {
+		required[name] = true
+	}
+	var values []genValue
+	if root.Title != "" {
+		values = append(values, genValue{element: "form-title", value: root.Title})
+	}
+	if root.Description != "" {
+		values = append(values, genValue{element: "form-desc", value: root.Description})
+	}
+	// properties that don't map onto any element are listed as comments, so that they aren't silently lost
+	var skipped []string
+	for _, prop := range root.Properties {
+		v, err := schemaElement(prop.name, prop.node)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("# skipped %s: %v", prop.name, err))
+			continue
+		}
+		v.required = required[prop.name]
+		values = append(values, v)
+	}
+	// the equals signs are lined up, like in the example format
+	var width int
+	for _, v := range values {
+		if len(v.formatLeft()) > width {
+			width = len(v.formatLeft())
+		}
+	}
+	var lines []string
+	for _, v := range values {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("%-*s = %s", width, v.formatLeft(), v.value)))
+	}
+	lines = append(lines, skipped...)
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// schemaElement picks the element for a property of a json schema
+func schemaElement(name string, node schemaNode) (genValue, error) {
+	v := genValue{title: name, constraints: make(map[string]string)}
+	if node.Title != "" {
+		v.title = node.Title
+	}
+	// the key keeps the property's name, whatever the title
+	if strings.ToLower(v.title) != name {
+		v.key = name
+	}
+	switch node.typeName() {
+	case "string":
+		if len(node.Enum) > 0 {
+			v.element, v.value = "select", schemaOptions(node.Enum)
+			return v, nil
+		}
+		switch node.Format {
+		case "email", "date", "time":
+			v.element = node.Format
+		case "uri", "url":
+			v.element = "url"
+		case "date-time":
+			v.element = "datetime-local"
+		default:
+			v.element = "input"
+			if node.MinLength != nil {
+				v.constraints["minlength"] = strconv.Itoa(*node.MinLength)
+			}
+			if node.MaxLength != nil {
+				v.constraints["maxlength"] = strconv.Itoa(*node.MaxLength)
+			}
+			// patterns with braces or commas can't be written in a {...} block
+			if node.Pattern != "" && strings.ContainsAny(node.Pattern, "{},") {
+				v.value = "pattern=" + node.Pattern
+				return v, nil
+			} else if node.Pattern != "" {
+				v.constraints["pattern"] = node.Pattern
+			}
+		}
+		v.value = escape(node.Description)
+	case "integer", "number":
+		v.element = "number"
+		var options []string
+		if node.Minimum != nil {
+			options = append(options, "min="+strconv.FormatFloat(*node.Minimum, 'f', -1, 64))
+		}
+		if node.Maximum != nil {
+			options = append(options, "max="+strconv.FormatFloat(*node.Maximum, 'f', -1, 64))
+		}
+		// any number, rather than whole numbers only
+		if node.typeName() == "number" {
+			options = append(options, "step=any")
+		}
+		v.value = strings.Join(options, ", ")
+	case "boolean":
+		v.element = "yesno"
+	case "array":
+		if node.Items == nil || len(node.Items.Enum) == 0 {
+			return v, fmt.Errorf("only lists of options (an array with an items enum) are supported")
+		}
+		v.element, v.value = "checkboxes", schemaOptions(node.Items.Enum)
+	default:
+		return v, fmt.Errorf("%q properties are not supported", node.typeName())
+	}
+	return v, nil
+}
+
+// schemaOptions writes the values of an enum as the comma-separated options of a select or checkboxes
+func schemaOptions(enum []interface{}) string {
+	var options []string
+	for _, value := range enum {
+		if value != nil {
+			options = append(options, escape(fmt.Sprint(value)))
+		}
+	}
+	return strings.Join(options, ", ")
+}
+
+// formatLeft writes the left-hand side of the element's line in a format file, e.g. `!input[Name]#name{maxlength=20}`
+func (v genValue) formatLeft() string {
+	if strings.HasPrefix(v.element, "form-") {
+		return v.element
+	}
+	left := v.element + "[" + v.title + "]"
+	if v.required {
+		left = "!" + left
+	}
+	if v.key != "" {
+		left += "#" + v.key
+	}
+	var constraints []string
+	for name, value := range v.constraints {
+		constraints = append(constraints, name+"="+escape(value))
+	}
+	if len(constraints) > 0 {
+		sort.Strings(constraints)
+		left += "{" + strings.Join(constraints, ", ") + "}"
+	}
+	return left
 }
 
 type Theme struct {
 	background, title, body string
+	// the palette used when the visitor prefers a dark color scheme
+	backgroundDark, titleDark, bodyDark string
+	font, headingFont string
+	// stylesheets of web fonts used by the fonts
+	fontImports []string
 }
 
 type StyleData struct {
 	Background, TitleColor, Body template.HTML
+	BackgroundDark, TitleColorDark, BodyDark template.HTML
+	// font-family lists can contain quotes, e.g. "Open Sans", which html/template only lets through as css
+	Font, HeadingFont template.CSS
+	FontImports []string
+	// the max-width of the form's rows, set with form-width
+	Width string
+	// hides the form-honeypot field
+	Honeypot bool
 }
 
 type TemplateData struct {
 	Header, Footer, Content template.HTML
 	Stylesheet template.CSS
 	Title string
+	// the language of the page, set on its <html>
+	Lang string
+}
+
+// ResponseData are the texts of the page shown after submitting the form, set with `response-title` and
+// `response-message`
+type ResponseData struct {
+	Title string
+	Message template.HTML
+	Lang string
 }
 
 var stylesheetTemplate = `<style>
+		{{ range .FontImports }} @import url("{{ . }}"); {{ end }}
 		html {
 			{{ if .Background }} background: {{ .Background }}; {{ end }}
 			{{ if .Body }} color: {{ .Body }}; {{ end }}
+			{{ if .Font }} font-family: {{ .Font }}; {{ end }}
 			padding-left: 2rem;
 			padding-right: 2rem;
 			padding-top: 1rem;
 		}
 		h1 {
 			{{ if .TitleColor }} color: {{ .TitleColor }}; {{ end }}
+			{{ if .HeadingFont }} font-family: {{ .HeadingFont }}; {{ end }}
 		}
 		* {
 			padding: 0;
@@ -87,24 +640,152 @@ var stylesheetTemplate = `<style>
 		}
 		div {
 			display: grid;
-			max-width: 600px;
+			max-width: {{ .Width }};
 			align-items: center;
 		}
+		{{ if .Honeypot }} .honeypot { display: none; } {{ end }}
+		.field-error { color: #c00; }
+		{{ if or .BackgroundDark .BodyDark .TitleColorDark }}
+		@media (prefers-color-scheme: dark) {
+			html {
+				{{ if .BackgroundDark }} background: {{ .BackgroundDark }}; {{ end }}
+				{{ if .BodyDark }} color: {{ .BodyDark }}; {{ end }}
+			}
+			h1 {
+				{{ if .TitleColorDark }} color: {{ .TitleColorDark }}; {{ end }}
+			}
+		}
+		{{ end }}
 </style>
 `
 
-func parseFormat(format string) []genValue {
-	pattern := regexp.MustCompile(`(form-\w+)|([!]?)(\S*)(\[.*\])([#]\S+)?`)
-	scanner := bufio.NewScanner(strings.NewReader(format))
+// parseFormat parses the contents of a format file. file is the path of an included file, which is mentioned in the
+// errors found in it
+func parseFormat(format, file string, strict bool) ([]genValue, []error) {
+	pattern := regexp.MustCompile(`(form-[\w-]+)|([!]?)(\S*)(\[.*\])([#]\S+)?`)
+	// editors on windows like to start files with a byte order mark, which would end up in the first element
+	format = strings.TrimPrefix(format, "\uFEFF")
+	lines, lineNumbers := joinContinuedLines(format)
 	var genList []genValue
-	for scanner.Scan() {
-		line := scanner.Text()
-		splitterIndex := strings.Index(line, "=")
-		left := strings.TrimSpace(line[0:splitterIndex])
-
-		var v genValue 
-		v.value = strings.TrimSpace(line[splitterIndex+1:])
+	// parsing carries on after a mistake, so that all of them can be reported at once
+	var errs []error
+	// a comment is a `#` at the start of a line, or a `#` preceded by whitespace in the value. a `#` directly
+	// following the title (`number[Moni]#amount`) is a key, and a value starting with `#` (`form-bg = #fff`) is kept
+	trailingComment := regexp.MustCompile(`\s+#.*$`)
+	constraintBlock := regexp.MustCompile(`\{([^}]*)\}`)
+	// the index in genList of the repeat[...] block currently being read, if any
+	repeatIndex := -1
+	// `@event = Merveilles Meetup` defines a variable, which values further down can refer to as @event
+	vars := make(map[string]string)
+	// the field that a `help = ...` on the next line would belong to
+	var helpTarget *genValue
+	add := func(v genValue) {
+		if repeatIndex >= 0 {
+			children := &genList[repeatIndex].children
+			*children = append(*children, v)
+			helpTarget = &(*children)[len(*children)-1]
+			return
+		}
+		genList = append(genList, v)
+		helpTarget = &genList[len(genList)-1]
+		if v.element == "repeat" {
+			repeatIndex = len(genList) - 1
+		}
+	}
+	for i, line := range lines {
+		// comments start with a `#` or `//`
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if repeatIndex >= 0 {
+			// the lines of a repeat[...] block are indented, and end with a blank line or an end-repeat
+			if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed == "end-repeat" {
+				repeatIndex = -1
+				continue
+			}
+			if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+				repeatIndex = -1
+			}
+		}
+		if strings.TrimSpace(line) == "" {
+			helpTarget = nil
+			continue
+		}
+		v := genValue{line: lineNumbers[i], file: file}
+		// directives like `section[Contact Info]` and `end-section` have no content, and so no equals sign
+		left := strings.TrimSpace(line)
+		splitterIndex := findSplitter(line)
+		if splitterIndex >= 0 {
+			left = strings.TrimSpace(line[0:splitterIndex])
+			v.value = strings.TrimSpace(line[splitterIndex+1:])
+			v.value = trailingComment.ReplaceAllString(v.value, "")
+		}
+		// only paragraphs keep the line breaks of continued lines
+		left = strings.ReplaceAll(left, "\n", " ")
+		if left != "form-desc" && left != "form-paragraph" && left != "response-message" {
+			v.value = strings.ReplaceAll(v.value, "\n", " ")
+		}
+		// `help = ...` belongs to the field on the line right before it
+		if left == "help" {
+			if helpTarget == nil {
+				errs = append(errs, newParseError(v.position(), line, "help has to directly follow the field it's for"))
+				continue
+			}
+			if err := substituteVariables(&v, vars); err != nil {
+				errs = append(errs, newParseError(v.position(), line, err.Error()))
+				continue
+			}
+			helpTarget.help = v.value
+			continue
+		}
+		helpTarget = nil
+		if block := constraintBlock.FindStringSubmatch(left); block != nil {
+			v.constraints, _ = parseOptions(block[1])
+			v.help = v.constraints["help"]
+			left = strings.TrimSpace(strings.Replace(left, block[0], "", 1))
+		}
+		// `@include shared/contact.mould` splices in the elements of another file (see spliceIncludes). it looks like a
+		// variable, so it's picked out before those
+		if left == "@include" || strings.HasPrefix(left, "@include ") || strings.HasPrefix(left, "@include\t") {
+			v.element = "include"
+			if splitterIndex < 0 {
+				v.value = trailingComment.ReplaceAllString(strings.TrimSpace(strings.TrimPrefix(left, "@include")), "")
+			}
+			if v.value == "" {
+				errs = append(errs, newParseError(v.position(), line, "@include needs the path of the file to include"))
+				continue
+			}
+			if err := substituteVariables(&v, vars); err != nil {
+				errs = append(errs, newParseError(v.position(), line, err.Error()))
+				continue
+			}
+			add(v)
+			continue
+		}
+		if strings.HasPrefix(left, "@") {
+			if !variableName.MatchString(left) {
+				errs = append(errs, newParseError(v.position(), line, fmt.Sprintf("invalid variable name %q, only letters, digits and _ are allowed", left)))
+				continue
+			}
+			vars[left[1:]] = unescape(v.value)
+			continue
+		}
 		matches := pattern.FindStringSubmatch(left)
+		if matches == nil {
+			// no title either, just an element
+			v.element = left
+			if splitterIndex < 0 && !directives[v.element] {
+				// more likely than a missing = is a misspelled directive, like end-sectoin
+				errs = append(errs, newParseError(v.position(), line, "missing '=' separator"+suggestElement(v.element)))
+				continue
+			}
+			if err := substituteVariables(&v, vars); err != nil {
+				errs = append(errs, newParseError(v.position(), line, err.Error()))
+				continue
+			}
+			add(v)
+			continue
+		}
 		if len(matches) > 2 && matches[2] == "!" {
 			v.required = true
 		}
@@ -121,266 +802,3718 @@ func parseFormat(format string) []genValue {
 			// remove initial #
 			v.key = strings.TrimSpace(matches[5][1:])
 		}
-		genList = append(genList, v)
+		splitExclusive(&v)
+		if splitterIndex < 0 && !directives[v.element] {
+			errs = append(errs, newParseError(v.position(), line, "missing '=' separator"))
+			continue
+		}
+		// a typo in the element would otherwise silently leave the element out of the form
+		if !elements[v.element] && !directives[v.element] {
+			err := newParseError(v.position(), line, fmt.Sprintf("unknown element %q%s", v.element, suggestElement(v.element)))
+			if strict {
+				errs = append(errs, err)
+				continue
+			}
+			fmt.Println("warning:", err)
+		}
+		if err := substituteVariables(&v, vars); err != nil {
+			errs = append(errs, newParseError(v.position(), line, err.Error()))
+			continue
+		}
+		add(v)
 	}
-	return genList
+	return genList, errs
 }
 
-var htmlTemplate = `<!DOCTYPE html>
-<html>
-	<head>
-		<title>{{ .Title }}</title>
-		{{ if .Stylesheet }} 
-		<style>
-			{{ .Stylesheet }} 
-		</style>
-		{{ end }}
-	</head>
-	<body>
-	{{ if .Header }} {{ .Header }} {{ end }}
-	{{ .Content }}
-	{{ if .Footer }} {{ .Footer }} {{ end }}
-	</body>
-</html>`
-
-var responseTemplate = `<!DOCTYPE html>
-<html>
-    <head>
-    <title>Form submitted</title>
-		%SENTINEL%
-    <body>
-			<h1>Response successful</h1>
-			<p>Your response: </p>
-			<pre>
-			<code>
-{{ .Data }}
-			</code>
-			</pre>
-			<p><b>Bookmark this page</b> as a receipt or if you want to review what you responded some time in the future</p>
-	</body>
-</html>`
+var (
+	variableName = regexp.MustCompile(`^@\w+$`)
+	// only an @ at the start of a word refers to a variable, others are part of e.g. an email address or a pattern
+	variableReference = regexp.MustCompile(`(^|[\s("'])@(\w+)`)
+)
 
-func formatKeyAndTitle(v genValue) (string, string) {
-	key := strings.ToLower(v.title)
-	title := strings.ReplaceAll(strings.Title(v.title), " ", "")
-	if len(v.key) > 0 {
-		key = v.key
-		title = strings.ReplaceAll(strings.Title(strings.ReplaceAll(v.key, "-", " ")), " ", "")
-	}
-	return key, title
+// substituteVariables replaces the @variables in the value of v with their values. this happens after the line has
+// been split up, and the values are escaped for the elements that split their content on commas and the like, so
+// that a variable is always taken as-is: a comma in it doesn't add an option to a radio
+func substituteVariables(v *genValue, vars map[string]string) error {
+	// these elements use their content as plain text
+	plain := (strings.HasPrefix(v.element, "form-") || strings.HasPrefix(v.element, "response-") || v.element == "include") &&
+		v.element != "form-desc" && v.element != "form-paragraph" && v.element != "response-message"
+	var err error
+	v.value = variableReference.ReplaceAllStringFunc(v.value, func(match string) string {
+		groups := variableReference.FindStringSubmatch(match)
+		value, ok := vars[groups[2]]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("undefined variable @%s", groups[2])
+			}
+			return match
+		}
+		if !plain {
+			value = escape(value)
+		}
+		return groups[1] + value
+	})
+	return err
 }
 
-func readFileAsString(fp string) (string, bool) {
-	if fp != "" {
+// spliceIncludes replaces the `@include other.mould` lines among values with the elements of the files they include,
+// which are resolved relative to dir. including are the files currently being included, to catch includes that
+// (eventually) include themselves
+func spliceIncludes(values []genValue, dir string, including []string, strict bool) ([]genValue, []error) {
+	var spliced []genValue
+	var errs []error
+	for _, v := range values {
+		if v.element != "include" {
+			spliced = append(spliced, v)
+			continue
+		}
+		fp := v.value
+		if !filepath.IsAbs(fp) {
+			fp = filepath.Join(dir, fp)
+		}
+		for _, includer := range including {
+			if sameFile(fp, includer) {
+				return nil, []error{fmt.Errorf("%s: @include %s: the file includes itself (%s)", v.position(), v.value, strings.Join(append(including, fp), " -> "))}
+			}
+		}
 		b, err := os.ReadFile(fp)
-		if err == nil {
-			return string(b), true
-		} else {
-			fmt.Printf("err reading %s", fp)
-			return "", false
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: @include %s: %w", v.position(), v.value, err))
+			continue
+		}
+		included, includeErrs := parseFormat(string(b), fp, strict)
+		if len(includeErrs) == 0 {
+			included, includeErrs = spliceIncludes(included, filepath.Dir(fp), append(including, fp), strict)
 		}
+		errs = append(errs, includeErrs...)
+		spliced = append(spliced, included...)
 	}
-	return "", false
+	return spliced, errs
 }
 
-const formPackageName = "myform"
-func main() {
-	var htmlList []string
-	var theme Theme
-	var setPassword string
-	setUser := "mouldy" // default user is "mouldy". only used if password is set, and can be changed with `form-user`
-	var pageTitle string
-	var formatFp string
-	var stylesheetFp string
-	var headerFp, footerFp string
-	flag.StringVar(&headerFp, "html-header", "", "a single html file containing all of the html that will be presented immediately above the form contents")
-	flag.StringVar(&footerFp, "html-footer", "", "a single html file containing all of the html that will be presented immediately below the form contents")
-	flag.StringVar(&stylesheetFp, "stylesheet", "", "a single css file containing styles that will be applied to the form (fully replaces mould's default styling)")
-	flag.StringVar(&formatFp, "input", "", "a file containing the form format to generate a form server using")
-	flag.Parse()
-	if formatFp == "" {
-		fmt.Println("must pass --input <file containing form format>")
-		os.Exit(0)
+// sameFile reports whether both paths point at the same file, however they were written
+func sameFile(a, b string) bool {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr != nil || bErr != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
 	}
-	b, err := os.ReadFile(formatFp)
-	if err != nil {
-		fmt.Println("issue when reading format file", err)
+	return os.SameFile(aInfo, bInfo)
+}
+
+// langPattern matches language tags, like de or pt-BR, as used by form-lang, --lang and translations
+var langPattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// translatableValues are the elements whose content is shown as text, and can be translated. the content of the
+// others ends up in the answers (like options), which have to be the same in every language
+var translatableValues = map[string]bool{
+	"form-title": true, "form-desc": true, "form-section": true, "form-paragraph": true,
+	"response-title": true, "response-message": true,
+	"input": true, "url": true, "tel": true, "email": true, "password": true, "textarea": true,
+}
+
+// translation picks the text in lang out of text with translations, like `your name|de:dein Name`. the text before
+// the first translation is the default, which is also used for languages without a translation of their own. a pipe
+// that isn't followed by a language tag and a colon is part of the text
+func translation(text, lang string) (string, bool) {
+	var texts []string
+	var langs []string
+	for _, part := range splitRaw(text, '|') {
+		tag, translated, ok := strings.Cut(part, ":")
+		if len(texts) > 0 && ok && langPattern.MatchString(tag) {
+			texts, langs = append(texts, translated), append(langs, tag)
+		} else if len(texts) > 0 {
+			texts[len(texts)-1] += "|" + part
+		} else {
+			texts, langs = append(texts, part), append(langs, "")
+		}
 	}
-	format := string(b)
+	if len(texts) == 1 {
+		return text, false
+	}
+	result := texts[0]
+	for i, tag := range langs {
+		if i > 0 && tag == lang {
+			result = texts[i]
+		}
+	}
+	return strings.TrimSpace(result), true
+}
 
-	values := parseFormat(format)
+// localize replaces the titles, help texts and (for elements showing it as text) contents of values with their
+// translation to lang, or their default text when lang is empty. the keys of translated fields are those of the
+// default language, so that the same go code handles the answers in every language
+func localize(values []genValue, lang string) ([]genValue, []error) {
+	var errs []error
+	for i, v := range values {
+		title, _ := translation(v.title, "")
+		if lang != "" && v.key == "" && title != "" {
+			values[i].key = strings.ToLower(title)
+		}
+		values[i].title, _ = translation(v.title, lang)
+		values[i].help, _ = translation(v.help, lang)
+		value, translated := translation(v.value, lang)
+		switch {
+		case strings.HasPrefix(v.value, "pattern="):
+			// the pipes of a pattern are alternatives, not translations
+		case translatableValues[v.element]:
+			values[i].value = value
+		case translated:
+			errs = append(errs, fmt.Errorf("%s: the content of %s elements can't be translated, only their title", v.position(), v.element))
+		}
+		children, childErrs := localize(append([]genValue{}, v.children...), lang)
+		values[i].children = children
+		errs = append(errs, childErrs...)
+	}
+	return values, errs
+}
 
-	f := NewFile(formPackageName)
-	var contentBits []Code
-	var answer []Code
-	var resParse []Code
-	for _, input := range values {
-		switch input.element {
-		case "form-title":
-			contentBits = append(contentBits, Id("Title").String())
-			htmlList = append(htmlList, fmt.Sprintf(`<h1>%s</h1>`, input.value))
-			pageTitle = input.value
-		case "form-desc":
-			contentBits = append(contentBits, Id("Description").String())
-			htmlList = append(htmlList, fmt.Sprintf(`<p>%s</p>`, input.value))
-		case "form-image":
-			contentBits = append(contentBits, Id("Image").String())
-			htmlList = append(htmlList, fmt.Sprintf(`<img src="%s">`, input.value))
-		case "form-password":
-			setPassword = input.value
-			// information used for basic auth, limiting access to the form
-			contentBits = append(contentBits, Id("Password").String())
-		case "form-user":
-			setUser = input.value
-			// information used for basic auth, limiting access to the form
-			contentBits = append(contentBits, Id("User").String())
-		case "form-bg":
-			theme.background = input.value
-		case "form-titlecolor":
-			theme.title = input.value
-		case "form-fg":
-			theme.body = input.value
+// splitExclusive takes the option marked with a leading ^ out of the options of a checkbox group:
+// `checkbox[Allergies] = Nuts, Gluten, ^None of the above`. the marked option excludes all others
+func splitExclusive(v *genValue) {
+	if v.element != "checkbox" && v.element != "checkboxes" {
+		return
+	}
+	options := splitRaw(v.value, ',')
+	for i, option := range options {
+		if trimmed := strings.TrimSpace(option); strings.HasPrefix(trimmed, "^") {
+			v.exclusive = strings.TrimSpace(trimmed[1:])
+			options[i] = " " + v.exclusive
 		}
 	}
+	v.value = strings.TrimSpace(strings.Join(options, ","))
+}
 
-	htmlList = append(htmlList, `<form action="/" method="post">`)
+// yamlMap is a mapping read from a yaml format file, keeping the line it started on for error messages
+type yamlMap struct {
+	keys []string
+	values map[string]interface{}
+	line int
+}
+
+type yamlLine struct {
+	indent int
+	text string
+	number int
+}
+
+// yamlParser reads the subset of yaml that form definitions need: nested mappings and lists, plain and quoted
+// strings, booleans and [inline, lists]. anchors, multi-line strings and multiple documents aren't supported
+type yamlParser struct {
+	lines []yamlLine
+	pos int
+}
+
+func parseYAML(src string) (interface{}, error) {
+	var p yamlParser
+	src = strings.TrimPrefix(src, "\uFEFF")
+	for i, line := range strings.Split(src, "\n") {
+		text := strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: yaml is indented with spaces, not tabs", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(text) - len(trimmed), text: trimmed, number: i + 1})
+	}
+	if len(p.lines) == 0 {
+		return nil, fmt.Errorf("the yaml file is empty")
+	}
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err == nil && p.pos < len(p.lines) {
+		err = fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return value, err
+}
+
+// parseBlock reads the list or mapping starting at the current line, which is indented by indent
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isListItem(p.lines[p.pos].text) {
+		return p.parseList(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseList(indent int) ([]interface{}, error) {
+	var list []interface{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		item := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		switch {
+		case item == "":
+			// the item is the block on the following lines
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				list = append(list, nil)
+				continue
+			}
+			value, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		case yamlKey(item) != "":
+			// `- type: input` starts a mapping, indented to where its first key is
+			p.lines[p.pos] = yamlLine{indent: indent + len(line.text) - len(item), text: item, number: line.number}
+			value, err := p.parseMap(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		default:
+			value, err := yamlScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.number, err)
+			}
+			list = append(list, value)
+			p.pos++
+		}
+	}
+	return list, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (yamlMap, error) {
+	m := yamlMap{values: make(map[string]interface{}), line: p.lines[p.pos].number}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		key := yamlKey(line.text)
+		if key == "" {
+			return m, fmt.Errorf("line %d: expected a `key: value` pair", line.number)
+		}
+		if _, ok := m.values[key]; ok {
+			return m, fmt.Errorf("line %d: %s is set twice", line.number, key)
+		}
+		m.keys = append(m.keys, key)
+		rest := strings.TrimSpace(line.text[len(key)+1:])
+		p.pos++
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			value, err := yamlScalar(rest)
+			if err != nil {
+				return m, fmt.Errorf("line %d: %w", line.number, err)
+			}
+			m.values[key] = value
+			continue
+		}
+		// the value is the block on the following lines. lists may be indented as far as their key
+		if p.pos < len(p.lines) && (p.lines[p.pos].indent > indent || (p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text))) {
+			value, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return m, err
+			}
+			m.values[key] = value
+			continue
+		}
+		m.values[key] = nil
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return m, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return m, nil
+}
+
+// yamlKey returns the key of a `key: value` line, or "" if it isn't one
+func yamlKey(text string) string {
+	i := strings.Index(text, ":")
+	if i <= 0 || (i+1 < len(text) && text[i+1] != ' ') || strings.ContainsAny(text[:i], `"'[{#`) {
+		return ""
+	}
+	return text[:i]
+}
+
+// yamlScalar reads a single value: a quoted or plain string, a boolean or an inline [list]
+func yamlScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		end := strings.LastIndex(text, `"`)
+		if end == 0 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		return strconv.Unquote(text[:end+1])
+	case strings.HasPrefix(text, "'"):
+		end := strings.LastIndex(text, "'")
+		if end == 0 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		return strings.ReplaceAll(text[1:end], "''", "'"), nil
+	case strings.HasPrefix(text, "["):
+		end := strings.LastIndex(text, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated list %s", text)
+		}
+		var list []interface{}
+		for _, item := range strings.Split(text[1:end], ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			value, err := yamlScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+	text = trailingYAMLComment.ReplaceAllString(text, "")
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return text, nil
+}
+
+var trailingYAMLComment = regexp.MustCompile(`\s+#.*$`)
+
+// parseYAMLFormat reads a form defined in yaml, as an alternative to the format file syntax:
+//
+//	title: Sticker order
+//	fields:
+//	  - type: input
+//	    label: Name
+//	    required: true
+//	    placeholder: First and last name
+//	  - type: radio
+//	    label: Size
+//	    options: [Small, Medium, Large]
+//
+// the top-level keys are the form-* (or response-*) settings, with or without their prefix. each field has a type
+// (its element), a label (its title), and optionally a key, required, help, and either a value (the right-hand side
+// of its line in a format file), a placeholder or a list of options. any other keys are its constraints
+func parseYAMLFormat(src string, strict bool) ([]genValue, []error) {
+	root, err := parseYAML(src)
+	if err != nil {
+		return nil, []error{err}
+	}
+	form, ok := root.(yamlMap)
+	if !ok {
+		return nil, []error{fmt.Errorf("the yaml has to be a mapping of form settings and fields")}
+	}
+	var values []genValue
+	var errs []error
+	for _, name := range form.keys {
+		if name == "fields" {
+			continue
+		}
+		element := name
+		if !elements[element] && elements["form-"+name] {
+			element = "form-" + name
+		}
+		if !(strings.HasPrefix(element, "form-") || strings.HasPrefix(element, "response-")) || !elements[element] {
+			errs = append(errs, fmt.Errorf("line %d: unknown form setting %q", form.line, name))
+			continue
+		}
+		values = append(values, genValue{element: element, value: fmt.Sprint(form.values[name]), line: form.line})
+	}
+	fields, ok := form.values["fields"].([]interface{})
+	if !ok {
+		return nil, append(errs, fmt.Errorf("line %d: the yaml needs a list of fields", form.line))
+	}
+	fieldValues, fieldErrs := yamlFields(fields, strict)
+	return append(values, fieldValues...), append(errs, fieldErrs...)
+}
+
+// yamlFields reads a list of fields from a yaml form definition
+func yamlFields(fields []interface{}, strict bool) ([]genValue, []error) {
+	var values []genValue
+	var errs []error
+	for _, item := range fields {
+		field, ok := item.(yamlMap)
+		if !ok {
+			errs = append(errs, fmt.Errorf("every field has to be a mapping with at least a type, got %v", item))
+			continue
+		}
+		v := genValue{line: field.line, constraints: make(map[string]string)}
+		for _, name := range field.keys {
+			value := field.values[name]
+			text := fmt.Sprint(value)
+			switch name {
+			case "type":
+				v.element = text
+			case "label":
+				v.title = text
+			case "key":
+				v.key = text
+			case "required":
+				v.required = value == true
+			case "help":
+				v.help = escape(text)
+			case "value":
+				v.value = text
+			case "placeholder":
+				v.value = escape(text)
+			case "options":
+				list, ok := value.([]interface{})
+				if !ok {
+					errs = append(errs, fmt.Errorf("line %d: options has to be a list", field.line))
+					continue
+				}
+				var options []string
+				for _, option := range list {
+					options = append(options, escape(fmt.Sprint(option)))
+				}
+				v.value = strings.Join(options, ", ")
+			case "fields":
+				// the elements of a repeat
+				list, ok := value.([]interface{})
+				if !ok {
+					errs = append(errs, fmt.Errorf("line %d: fields has to be a list", field.line))
+					continue
+				}
+				children, childErrs := yamlFields(list, strict)
+				v.children = children
+				errs = append(errs, childErrs...)
+			default:
+				v.constraints[name] = text
+			}
+		}
+		if v.element == "" {
+			errs = append(errs, fmt.Errorf("line %d: the field has no type", field.line))
+			continue
+		}
+		if !elements[v.element] && !directives[v.element] {
+			err := fmt.Errorf("line %d: unknown element %q%s", field.line, v.element, suggestElement(v.element))
+			if strict {
+				errs = append(errs, err)
+				continue
+			}
+			fmt.Println("warning:", err)
+		}
+		splitExclusive(&v)
+		values = append(values, v)
+	}
+	return values, errs
+}
+
+// parseError is a mistake in the format file, pointing out where it was made
+type parseError struct {
+	// the line (and for included files, the file) of the mistake
+	position string
+	column int
+	// the offending line of the format file
+	text string
+	msg string
+}
+
+// newParseError creates a parseError pointing at the first non-whitespace column of the line
+func newParseError(position, text, msg string) parseError {
+	column := len(text) - len(strings.TrimLeft(text, " \t")) + 1
+	return parseError{position: position, column: column, text: strings.TrimSpace(text), msg: msg}
+}
+
+func (err parseError) Error() string {
+	return fmt.Sprintf("%s, column %d: %s\n\t%s", err.position, err.column, err.msg, err.text)
+}
+
+// parseErrors are all the mistakes found in a format file
+type parseErrors []error
+
+func (errs parseErrors) Error() string {
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// elements are all of the elements that can be used in a format file, other than the directives
+var elements = map[string]bool{
+	"form-title":           true,
+	"form-desc":            true,
+	"form-image":           true,
+	"form-password":        true,
+	"form-user":            true,
+	"form-bg":              true,
+	"form-titlecolor":      true,
+	"form-fg":              true,
+	"form-bg-dark":         true,
+	"form-titlecolor-dark": true,
+	"form-width":           true,
+	"form-font":            true,
+	"form-headingfont":     true,
+	"form-fg-dark":         true,
+	"form-section":         true,
+	"form-action":          true,
+	"form-method":          true,
+	"form-honeypot":        true,
+	"form-maxbody":         true,
+	"form-ratelimit":       true,
+	"form-redirect":        true,
+	"form-lang":            true,
+	"include":              true,
+	"form-paragraph":       true,
+	"response-title":       true,
+	"response-message":     true,
+	"textarea":             true,
+	"suggest":              true,
+	"input":                true,
+	"url":                  true,
+	"tel":                  true,
+	"email":                true,
+	"password":             true,
+	"hidden":               true,
+	"display":              true,
+	"number":               true,
+	"range":                true,
+	"money":                true,
+	"color":                true,
+	"yesno":                true,
+	"consent":              true,
+	"multiselect":          true,
+	"file":                 true,
+	"date":                 true,
+	"time":                 true,
+	"datetime":             true,
+	"datetime-local":       true,
+	"radio":                true,
+	"checkbox":             true,
+	"checkboxes":           true,
+	"likert":               true,
+	"matrix":               true,
+	"country":              true,
+	"repeat":               true,
+	"rank":                 true,
+	"select":               true,
+}
+
+// directives are the elements that have no content, and are written without an equals sign
+var directives = map[string]bool{
+	"section":     true,
+	"end-section": true,
+	"end-repeat":  true,
+}
+
+// suggestElement suggests the known element closest to a misspelled one, e.g. ` (did you mean "textarea"?)` for
+// textara. it's empty when no element is close enough to be what was meant
+func suggestElement(element string) string {
+	if elements[element] || directives[element] {
+		return ""
+	}
+	var closest string
+	// a couple of typos, a few more for long names
+	closestDistance := 2 + len(element)/6
+	for _, known := range []map[string]bool{elements, directives} {
+		for name := range known {
+			d := editDistance(element, name)
+			if d < closestDistance || (d == closestDistance && (closest == "" || name < closest)) {
+				closest, closestDistance = name, d
+			}
+		}
+	}
+	if closest == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", closest)
+}
+
+// editDistance is the levenshtein distance between a and b: the least amount of inserted, deleted or replaced
+// characters that turn one into the other
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			// deleting, inserting or replacing a character, whichever is cheapest
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+var htmlTemplate = `<!DOCTYPE html>
+<html{{ if .Lang }} lang="{{ .Lang }}"{{ end }}>
+	<head>
+		<meta charset="utf-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1">
+		<title>{{ .Title }}</title>
+		{{ if .Stylesheet }} 
+		<style>
+			{{ .Stylesheet }} 
+		</style>
+		{{ end }}
+	</head>
+	<body>
+	{{ if .Header }} {{ .Header }} {{ end }}
+	{{ .Content }}
+	{{ if .Footer }} {{ .Footer }} {{ end }}
+	</body>
+</html>`
+
+var responseTemplate = `<!DOCTYPE html>
+<html[[ if .Lang ]] lang="[[ .Lang ]]"[[ end ]]>
+    <head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>[[ .Title ]]</title>
+		%SENTINEL%
+    </head>
+    <body>
+			<h1>[[ .Title ]]</h1>
+			<p>Your response: </p>
+			<pre>
+			<code>
+{{ .Data }}
+			</code>
+			</pre>
+			<p>[[ .Message ]]</p>
+	</body>
+</html>`
+
+// the texts of the response page, unless they're changed with `response-title` and `response-message`
+const defaultResponseTitle = "Response successful"
+const defaultResponseMessage = `<b>Bookmark this page</b> as a receipt or if you want to review what you responded some time in the future`
+
+// escapable are the characters that can be escaped with a backslash in the content, to keep them from separating
+// options, e.g. `radio[Team size] = 1-10, 11-999, 1\,000 or more`
+const escapable = ",=;|"
+
+// splitRaw splits content on every sep that isn't escaped with a backslash, keeping the escapes in the parts so that
+// they can be split again
+func splitRaw(content string, sep byte) []string {
+	var parts []string
+	var start int
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\\' && i+1 < len(content) && strings.IndexByte(escapable, content[i+1]) >= 0 {
+			i++
+			continue
+		}
+		if content[i] == sep {
+			parts = append(parts, content[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, content[start:])
+}
+
+// splitOptions splits content into its options, separated by sep. the options are trimmed and unescaped, and empty
+// ones (e.g. from a trailing comma) are left out
+func splitOptions(content string, sep byte) []string {
+	var options []string
+	for _, option := range splitRaw(content, sep) {
+		if option = strings.TrimSpace(unescape(option)); option != "" {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// escape backslash-escapes the separators in content, the inverse of unescape
+func escape(content string) string {
+	for _, c := range escapable {
+		content = strings.ReplaceAll(content, string(c), `\`+string(c))
+	}
+	return content
+}
+
+// unescape removes the backslashes escaping separators in the content
+func unescape(content string) string {
+	for _, c := range escapable {
+		content = strings.ReplaceAll(content, `\`+string(c), string(c))
+	}
+	return content
+}
+
+// checkRedirectPath checks that path is a local path that the form server can redirect to, and isn't taken by any of
+// its other routes
+func checkRedirectPath(path string) error {
+	u, err := url.Parse(path)
+	if err != nil || !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, "\\") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q is not a local path, e.g. /thanks", path)
+	}
+	if path == "/" || path == "/export.csv" || strings.HasPrefix(path, "/responder/") {
+		return fmt.Errorf("%q is already used by the form server", path)
+	}
+	return nil
+}
+
+// appendHelp adds the help text of input to its html, htmlList[start:]: a <small> at the end of the element's div,
+// which its inputs point to with aria-describedby so that screen readers read it out along with them
+func appendHelp(htmlList []string, start int, input genValue) ([]string, error) {
+	end := len(htmlList) - 1
+	if end < start || htmlList[end] != "</div>" {
+		return nil, fmt.Errorf("%s: help text can't be shown for %s elements", input.position(), input.element)
+	}
+	key, _ := formatKeyAndTitle(input)
+	helpId := slugify(key) + "-help"
+	for i := start; i < end; i++ {
+		for _, tag := range []string{"<input ", "<textarea ", "<select "} {
+			if strings.HasPrefix(htmlList[i], tag) {
+				htmlList[i] = fmt.Sprintf(`%saria-describedby="%s" %s`, tag, helpId, strings.TrimPrefix(htmlList[i], tag))
+			}
+		}
+	}
+	help := fmt.Sprintf(`<small id="%s">%s</small>`, helpId, html.EscapeString(unescape(input.help)))
+	return append(htmlList[:end], help, "</div>"), nil
+}
+
+// conditionField finds the field that a `{if=key=value}` (or `{show-if=key==value}`) condition depends on, which has
+// to come before the field with the condition, and be answered with text, like a radio or select. keys can be
+// written slugified, so that `Attending as` can be referred to as attending-as
+func conditionField(condition, separator string, fields []answerField, keyLines map[string]string) (answerField, string, error) {
+	parts := strings.SplitN(condition, separator, 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return answerField{}, "", fmt.Errorf("invalid condition %q, expected e.g. attending-as%sbusiness", condition, separator)
+	}
+	key, value := parts[0], parts[1]
+	for _, field := range fields {
+		if field.key != key && slugify(field.key) != key {
+			continue
+		}
+		if field.kind != "string" {
+			return answerField{}, "", fmt.Errorf("can't depend on %q, only fields answered with text (like radio or select) can be used in conditions", key)
+		}
+		return field, value, nil
+	}
+	for other, line := range keyLines {
+		if other == key || slugify(other) == key {
+			return answerField{}, "", fmt.Errorf("can't depend on %q, which comes later on %s", key, line)
+		}
+	}
+	return answerField{}, "", fmt.Errorf("can't depend on %q, there's no field with that key", key)
+}
+
+// conditionScript shows the fields with a data-show-if attribute while their condition is met, and hides them otherwise.
+// the inputs of hidden fields are disabled, so that they are neither required nor posted. a field depending on a
+// hidden one is hidden too, as disabled inputs aren't part of the form data
+const conditionScript = `<script>
+(form => {
+	const update = () => form.querySelectorAll('[data-show-if]').forEach(field => {
+		const split = field.dataset.showIf.indexOf('==')
+		const shown = new FormData(form).getAll(field.dataset.showIf.slice(0, split)).includes(field.dataset.showIf.slice(split + 2))
+		field.style.display = shown ? '' : 'none'
+		field.querySelectorAll('input, select, textarea').forEach(input => input.disabled = !shown)
+	})
+	form.addEventListener('change', update)
+	update()
+})(document.currentScript.closest('form'))
+</script>`
+
+// escapeParagraph escapes the text of a paragraph, keeping its line breaks
+func escapeParagraph(text string) string {
+	return strings.ReplaceAll(html.EscapeString(unescape(text)), "\n", "<br>")
+}
+
+// splitDefault separates a trailing `default=` option, which pre-fills text fields, from the rest of the content:
+// `input[City] = Where do you live?, default=Berlin`, or given as a constraint: `input[City]{default=Berlin} = ...`
+func splitDefault(input genValue) (string, string) {
+	content := input.value
+	var placeholder, defaultValue string
+	if value, ok := input.constraints["default"]; ok {
+		// a `{default=...}` constraint keeps the whole right-hand side as the placeholder
+		placeholder, defaultValue = unescape(content), value
+	} else if strings.HasPrefix(content, "default=") {
+		defaultValue = unescape(strings.TrimPrefix(content, "default="))
+	} else if i := strings.LastIndex(content, ", default="); i >= 0 {
+		placeholder, defaultValue = unescape(strings.TrimSpace(content[:i])), unescape(strings.TrimSpace(content[i+len(", default="):]))
+	} else {
+		placeholder = unescape(content)
+	}
+	// and a `{placeholder=...}` constraint takes precedence over the right-hand side
+	if value, ok := input.constraints["placeholder"]; ok {
+		placeholder = value
+	}
+	return placeholder, defaultValue
+}
+
+// findSplitter returns the index of the equals sign separating the element from its content, skipping the ones
+// inside of a {...} constraint block. -1 if there is none
+func findSplitter(line string) int {
+	var inBlock bool
+	for i, r := range line {
+		switch r {
+		case '{':
+			inBlock = true
+		case '}':
+			inBlock = false
+		case '=':
+			if !inBlock {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// maxConstraint reads the `{max=N}` constraint of a multiple choice element, which limits how many options may be
+// picked. 0 if there is no limit
+func maxConstraint(input genValue) (int, error) {
+	value, ok := input.constraints["max"]
+	if !ok {
+		return 0, nil
+	}
+	max, err := strconv.Atoi(value)
+	if err != nil || max < 1 {
+		return 0, fmt.Errorf("%s[%s]: invalid max %q, expected a positive number", input.element, input.title, value)
+	}
+	return max, nil
+}
+
+// maxHint is appended to the label of a multiple choice element limited to max options, so that respondents know
+// about the limit before picking
+func maxHint(max int) string {
+	if max == 0 {
+		return ""
+	}
+	return fmt.Sprintf(` <small>(pick up to %d)</small>`, max)
+}
+
+// joinContinuedLines joins lines ending with a `\` with the line after it, so that long values can be split over
+// several lines:
+//	form-desc = Hey! Hello! \
+//	            This is a nonsensical form
+//
+// the joined lines are separated by a newline, which parseFormat turns into a space everywhere but in paragraphs
+// (form-desc, form-paragraph and response-message), where they're line breaks. the joined lines are returned along with the line numbers they start on
+func joinContinuedLines(format string) ([]string, []int) {
+	var lines []string
+	var lineNumbers []int
+	var continued string
+	var start int
+	scanner := bufio.NewScanner(strings.NewReader(format))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		// the scanner drops the \r of \r\n line endings, this is for any stray ones left over
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if continued != "" {
+			line = continued + "\n" + strings.TrimSpace(line)
+			continued = ""
+		} else {
+			start = lineNumber
+		}
+		if trimmed := strings.TrimRight(line, " \t"); strings.HasSuffix(trimmed, "\\") {
+			continued = strings.TrimRight(strings.TrimSuffix(trimmed, "\\"), " \t")
+			continue
+		}
+		lines = append(lines, line)
+		lineNumbers = append(lineNumbers, start)
+	}
+	// a continuation on the last line has nothing to continue onto
+	if continued != "" {
+		lines = append(lines, continued)
+		lineNumbers = append(lineNumbers, start)
+	}
+	return lines, lineNumbers
+}
+
+func formatKeyAndTitle(v genValue) (string, string) {
+	key := strings.ToLower(v.title)
+	title := identifier(v.title)
+	if len(v.key) > 0 {
+		key = v.key
+		title = identifier(v.key)
+	}
+	return strings.Map(keyCharacter, key), title
+}
+
+// keyCharacter drops the characters that a key can't have, as it ends up as-is in html attributes, the actions of
+// the form's template, the strings of its scripts and the json tags of FormAnswer, which end at a comma:
+// `input[Your "quote", please]` gets the key `your quote please`
+func keyCharacter(r rune) rune {
+	if strings.ContainsRune("\"'`<>&\\{},", r) {
+		return -1
+	}
+	return r
+}
+
+// identifier turns text into an exported go identifier by title-casing its words and dropping everything that
+// can't be part of one, e.g. "E-mail (work)" becomes "EMailWork". identifiers that wouldn't be exported, like
+// "2nd address", get a Field prefix: "Field2ndAddress". returns "" if text has no letters or digits at all
+func identifier(text string) string {
+	var ident strings.Builder
+	wordStart := true
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			wordStart = true
+			continue
+		}
+		if wordStart {
+			r = unicode.ToUpper(r)
+			wordStart = false
+		}
+		ident.WriteRune(r)
+	}
+	if ident.Len() == 0 {
+		return ""
+	}
+	if first, _ := utf8.DecodeRuneInString(ident.String()); !unicode.IsUpper(first) {
+		return "Field" + ident.String()
+	}
+	return ident.String()
+}
+
+// slugify lowercases text and replaces everything that isn't a letter or digit with dashes, e.g. "How was the
+// event?" becomes "how-was-the-event"
+func slugify(text string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slug.WriteRune(r)
+		} else {
+			slug.WriteRune('-')
+		}
+	}
+	return strings.Trim(regexp.MustCompile(`-+`).ReplaceAllString(slug.String(), "-"), "-")
+}
+
+// parseOptions reads content of the form `min=1, max=5, value=1` into a map of its options, as well as into html
+// attributes (in the order they were written)
+func parseOptions(content string) (map[string]string, string) {
+	options := make(map[string]string)
+	var attributes string
+	for _, optionPair := range splitRaw(content, ',') {
+		optionPair = strings.TrimSpace(optionPair)
+		if optionPair == "" {
+			continue
+		}
+		parts := splitRaw(optionPair, '=')
+		name, value := unescape(parts[0]), unescape(strings.Join(parts[1:], "="))
+		options[name] = value
+		attributes += fmt.Sprintf(`%s="%s" `, name, html.EscapeString(value))
+	}
+	return options, attributes
+}
+
+// checkNumberOptions checks the options of a number or range: only min, max, step and value can be set, and all of
+// them to a number (step can also be "any")
+func checkNumberOptions(options map[string]string) error {
+	var names []string
+	for name := range options {
+		names = append(names, name)
+	}
+	// in the order they're checked, so that the same mistake always gets the same error
+	sort.Strings(names)
+	for _, name := range names {
+		value := options[name]
+		switch name {
+		case "min", "max", "step", "value":
+		default:
+			return fmt.Errorf("unknown option %q, expected min, max, step or value", name)
+		}
+		if value == "" {
+			return fmt.Errorf("option %s has no value, expected e.g. %s=1", name, name)
+		}
+		if _, err := strconv.ParseFloat(value, 64); err != nil && !(name == "step" && value == "any") {
+			return fmt.Errorf("option %s=%s is not a number", name, value)
+		}
+	}
+	return nil
+}
+
+// splitFlag removes a bare flag, like `showvalue`, from comma-separated options, reporting whether it was there
+func splitFlag(content, flag string) (string, bool) {
+	var kept []string
+	var found bool
+	for _, option := range splitRaw(content, ',') {
+		if strings.TrimSpace(option) == flag {
+			found = true
+			continue
+		}
+		kept = append(kept, option)
+	}
+	return strings.Join(kept, ","), found
+}
+
+// splitOtherOption removes a `+other` marker from the options of a radio or select, reporting whether there was one
+func splitOtherOption(options []string) ([]string, bool) {
+	var kept []string
+	var other bool
+	for _, option := range options {
+		if strings.TrimSpace(option) == "+other" {
+			other = true
+			continue
+		}
+		kept = append(kept, option)
+	}
+	return kept, other
+}
+
+// otherRequiredScript is the inline js that makes the free text input of an "other" option required, but only
+// while "other" is picked
+func otherRequiredScript(key string) string {
+	return fmt.Sprintf(`document.getElementById(%s).required = this.value == 'other'`, jsString(key+"-other"))
+}
+
+// jsString quotes text as a string of the inline js in an html attribute, like an onchange
+func jsString(text string) string {
+	return html.EscapeString("'" + template.JSEscapeString(text) + "'")
+}
+
+// appendOtherField adds the free text field answering an "other" option, which is only filled in when "other" was
+// actually picked, so that text typed before picking something else isn't stored
+func appendOtherField(fields []answerField, resParse []Code, key, title string) ([]answerField, []Code) {
+	fields = append(fields, answerField{key: key + "-other", title: title + "Other", kind: "string"})
+	resParse = append(resParse, If(Id("answer").Dot(title).Op("==").Lit("other")).Block(
+		Id("answer").Dot(title+"Other").Op("=").Id("req").Dot("PostFormValue").Call(Lit(key+"-other")),
+	).Else().Block(
+		Id("answer").Dot(title+"Other").Op("=").Lit(""),
+	))
+	return fields, resParse
+}
+
+// parseTimeCode generates the ParsePost code for reading a posted date and/or time into a time.Time, using the
+// layout the browser posts it in. an empty (optional) field is left at the zero time
+func parseTimeCode(key, title, layout string) Code {
+	return If(Id("v").Op(":=").Id("req").Dot("PostFormValue").Call(Lit(key)), Id("v").Op("!=").Lit("")).BlockFunc(func(g *Group) {
+		if strings.HasSuffix(layout, "15:04") {
+			// times are posted without seconds, unless the input's step is less than a minute
+			g.Id("layout").Op(":=").Lit(layout)
+			g.If(Len(Id("v")).Op(">").Len(Id("layout"))).Block(Id("layout").Op("+=").Lit(":05"))
+			g.List(Id("t"), Err()).Op(":=").Qual("time", "Parse").Call(Id("layout"), Id("v"))
+		} else {
+			g.List(Id("t"), Err()).Op(":=").Qual("time", "Parse").Call(Lit(layout), Id("v"))
+		}
+		g.If(Err().Op("!=").Nil()).Block(
+			Return(fieldError(key, Err().Dot("Error").Call())),
+		)
+		g.Id("answer").Dot(title).Op("=").Id("t")
+	})
+}
+
+// parseByteSize parses a size like `500`, `500b`, `200kb` or `5mb` into bytes
+func parseByteSize(size string) (int64, error) {
+	size = strings.ToLower(strings.TrimSpace(size))
+	var multiplier int64 = 1
+	if strings.HasSuffix(size, "kb") {
+		multiplier = 1 << 10
+	} else if strings.HasSuffix(size, "mb") {
+		multiplier = 1 << 20
+	}
+	n, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(size, "kb"), "mb"), "b"), 10, 64)
+	return n * multiplier, err
+}
+
+// checkWidth checks that the value of form-width is a length in px, rem, em or %, which is what a form's width is
+// sensibly given in
+func checkWidth(width string) error {
+	match := regexp.MustCompile(`^(\d+(?:\.\d+)?)(px|rem|em|%)$`).FindStringSubmatch(width)
+	if match == nil {
+		return fmt.Errorf("%q is not a width, expected a number of px, rem, em or %%, e.g. 800px", width)
+	}
+	n, _ := strconv.ParseFloat(match[1], 64)
+	if n == 0 || (match[2] == "%" && n > 100) {
+		return fmt.Errorf("%q is not a usable width", width)
+	}
+	return nil
+}
+
+// parseFont splits the value of form-font or form-headingfont into the url of a web font's stylesheet, if it starts
+// with one, and the font-family list: `https://fonts.googleapis.com/css2?family=Inter Inter, sans-serif`
+func parseFont(value string) (string, string, error) {
+	var fontImport string
+	font := value
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		fontImport = strings.Fields(value)[0]
+		font = strings.TrimSpace(strings.TrimPrefix(value, fontImport))
+	}
+	if font == "" {
+		return "", "", fmt.Errorf("missing font family, expected e.g. `Inter, sans-serif`")
+	}
+	// the font-family list is put into the stylesheet as-is, so it can't be allowed to end the declaration
+	if strings.ContainsAny(font, ";{}<>") {
+		return "", "", fmt.Errorf("%q is not a list of font families", font)
+	}
+	return fontImport, font, nil
+}
+
+// fieldError generates a *FieldError, for a mistake in the answer to the field with key
+func fieldError(key string, message Code) Code {
+	return Op("&").Id("FieldError").Values(Dict{Id("Key"): Lit(key), Id("Message"): message})
+}
+
+// checkNumber generates the checks of Validate() for the number or amount of money answering the field with key: the
+// bounds when it was answered, and whether it was answered at all when it's required
+func checkNumber(key, title string, required bool, bounds []Code) []Code {
+	if len(bounds) == 0 && !required {
+		return nil
+	}
+	answered := If(Id("answer").Dot("wasAnswered").Call(Lit(key), Id("answer").Dot(title).Op("!=").Lit(0))).Block(bounds...)
+	if required {
+		answered.Else().Block(invalid(key, Lit("required")))
+	}
+	return []Code{answered}
+}
+
+// invalid generates adding a FieldError for the field with key to the mistakes found by Validate()
+func invalid(key string, message Code) Code {
+	return Id("errs").Op("=").Append(Id("errs"), Id("FieldError").Values(Dict{Id("Key"): Lit(key), Id("Message"): message}))
+}
+
+// escapeActions escapes what would be taken for actions when the form server parses the form html as a template
+func escapeActions(text string) string {
+	return strings.ReplaceAll(text, "{{", "{{`{{`}}")
+}
+
+var (
+	inputTag = regexp.MustCompile(`<input [^>]*>`)
+	textareaTag = regexp.MustCompile(`(?s)(<textarea [^>]*>)(.*?)(</textarea>)`)
+	selectTag = regexp.MustCompile(`(?s)<select [^>]*>.*?</select>`)
+	optionTag = regexp.MustCompile(`<option [^>]*>`)
+	errorSlot = regexp.MustCompile(`<small class="field-error" id="([^"]*)" data-for="([^"]*)"></small>`)
+	tagAttribute = regexp.MustCompile(`\s(type|name|value)="([^"]*)"`)
+	valueAttribute = regexp.MustCompile(`\svalue="([^"]*)"`)
+)
+
+// attributes returns the type, name and value attributes of an html tag, unescaped
+func attributes(tag string) map[string]string {
+	found := make(map[string]string)
+	for _, match := range tagAttribute.FindAllStringSubmatch(tag, -1) {
+		found[match[1]] = html.UnescapeString(match[2])
+	}
+	return found
+}
+
+// templateActions turns the form html into the template the form server renders the form with (see FormPage):
+// filling in the csrf token, the answers when the form is shown again after a mistake, and what's wrong with them.
+// the html has to have gone through escapeActions already
+func templateActions(content string) string {
+	content = strings.Replace(content, csrfPlaceholder, "{{ .CSRFToken }}", 1)
+	content = strings.Replace(content, `<div id="form-errors" role="alert" aria-live="assertive"></div>`,
+		`<div id="form-errors" role="alert" aria-live="assertive">{{ range $key, $message := .Errors }}<p>{{ $key }}: {{ $message }}</p>{{ end }}</div>`, 1)
+	content = errorSlot.ReplaceAllStringFunc(content, func(slot string) string {
+		match := errorSlot.FindStringSubmatch(slot)
+		return fmt.Sprintf(`{{ with .Error %s }}<small class="field-error" id="%s">{{ . }}</small>{{ end }}`, strconv.Quote(html.UnescapeString(match[2])), match[1])
+	})
+	content = inputTag.ReplaceAllStringFunc(content, func(tag string) string {
+		attrs := attributes(tag)
+		name, ok := attrs["name"]
+		if !ok {
+			return tag
+		}
+		switch attrs["type"] {
+		case "hidden", "file", "password":
+			// never filled in again: hidden fields keep their value, and files and passwords can't be
+			return tag
+		case "radio", "checkbox":
+			// a checkbox without a value is posted as "on"
+			value, ok := attrs["value"]
+			if !ok {
+				value = "on"
+			}
+			return strings.Replace(tag, "<input ", fmt.Sprintf(`<input {{ if .Checked %s %s }}checked{{ end }} `, strconv.Quote(name), strconv.Quote(value)), 1)
+		}
+		restored := fmt.Sprintf(`{{ .Value %s }}`, strconv.Quote(name))
+		if _, ok := attrs["value"]; ok {
+			// a default value is only shown the first time around
+			return valueAttribute.ReplaceAllStringFunc(tag, func(value string) string {
+				return fmt.Sprintf(` value="{{ if .Values }}%s{{ else }}%s{{ end }}"`, restored, valueAttribute.FindStringSubmatch(value)[1])
+			})
+		}
+		return strings.Replace(tag, "<input ", fmt.Sprintf(`<input value="%s" `, restored), 1)
+	})
+	content = textareaTag.ReplaceAllStringFunc(content, func(textarea string) string {
+		match := textareaTag.FindStringSubmatch(textarea)
+		return fmt.Sprintf(`%s{{ if .Values }}{{ .Value %s }}{{ else }}%s{{ end }}%s`, match[1], strconv.Quote(attributes(match[1])["name"]), match[2], match[3])
+	})
+	content = selectTag.ReplaceAllStringFunc(content, func(selectHTML string) string {
+		name := strconv.Quote(attributes(selectHTML[:strings.Index(selectHTML, ">")])["name"])
+		return optionTag.ReplaceAllStringFunc(selectHTML, func(option string) string {
+			return strings.Replace(option, "<option ", fmt.Sprintf(`<option {{ if .Checked %s %s }}selected{{ end }} `, name, strconv.Quote(attributes(option)["value"])), 1)
+		})
+	})
+	return content
+}
+
+func readFileAsString(fp string) (string, bool) {
+	if fp != "" {
+		b, err := os.ReadFile(fp)
+		if err == nil {
+			return string(b), true
+		} else {
+			fmt.Printf("err reading %s", fp)
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// csrfPlaceholder marks where the csrf token goes in the form html, which is filled in by the form server
+const csrfPlaceholder = "%CSRF_TOKEN%"
+
+// serverOptions are the directives changing how the generated form server behaves
+type serverOptions struct {
+	// where the response page is shown after submitting the form, set with form-redirect
+	redirectPath string
+	// how many responses a client may submit per period, set with form-ratelimit. no limit when 0
+	rateLimit int
+	ratePeriod time.Duration
+	// the name of the hidden field that only bots fill in, set with form-honeypot
+	honeypot string
+	// the language of the form, set with form-lang, and the languages it's translated to, set with --lang
+	lang string
+	langs []string
+}
+
+// ratePeriods are the periods a form-ratelimit can be given in
+var ratePeriods = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// parseRateLimit reads a rate limit of the form `5/minute`
+func parseRateLimit(content string) (int, time.Duration, error) {
+	parts := strings.Split(content, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%q is not a rate limit, expected e.g. 5/minute", content)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit < 1 {
+		return 0, 0, fmt.Errorf("%q is not a rate limit, the amount of responses has to be a positive number", content)
+	}
+	period, ok := ratePeriods[strings.TrimSuffix(strings.TrimSpace(parts[1]), "s")]
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not a rate limit, the period has to be one of second, minute, hour or day", content)
+	}
+	return limit, period, nil
+}
+
+// generateRateLimiter generates the per-client rate limiting of responses: every ip address gets a rate.Limiter
+// allowing bursts of up to limit responses, which it regains at limit responses per period
+func generateRateLimiter(s *File, limit int, period time.Duration) {
+	s.Const().Defs(
+		Id("limitBurst").Op("=").Lit(limit),
+		Comment("responses per second"),
+		Id("limitRate").Op("=").Qual("golang.org/x/time/rate", "Limit").Call(Lit(float64(limit)/period.Seconds())),
+	)
+	s.Var().Defs(
+		Id("limitersMu").Qual("sync", "Mutex"),
+		Id("limiters").Op("=").Make(Map(String()).Op("*").Qual("golang.org/x/time/rate", "Limiter")),
+		Comment("starts forgetLimiters once, however often Handler() is called"),
+		Id("forgetting").Qual("sync", "Once"),
+	)
+	s.Comment("allowResponse reports whether the client making the request may submit another response")
+	s.Func().Id("allowResponse").Params(Id("req").Op("*").Qual("net/http", "Request")).Bool().Block(
+		List(Id("ip"), Id("_"), Err()).Op(":=").Qual("net", "SplitHostPort").Call(Id("req").Dot("RemoteAddr")),
+		If(Err().Op("!=").Nil()).Block(
+			Id("ip").Op("=").Id("req").Dot("RemoteAddr"),
+		),
+		Id("limitersMu").Dot("Lock").Call(),
+		Defer().Id("limitersMu").Dot("Unlock").Call(),
+		List(Id("l"), Id("ok")).Op(":=").Id("limiters").Index(Id("ip")),
+		If(Op("!").Id("ok")).Block(
+			Id("l").Op("=").Qual("golang.org/x/time/rate", "NewLimiter").Call(Id("limitRate"), Id("limitBurst")),
+			Id("limiters").Index(Id("ip")).Op("=").Id("l"),
+		),
+		Return(Id("l").Dot("Allow").Call()),
+	)
+	s.Comment("forgetLimiters periodically drops the limiters that are full again, which are no different from new ones, so")
+	s.Comment("that the limiters don't pile up")
+	s.Func().Id("forgetLimiters").Params().Block(
+		For(Range().Qual("time", "Tick").Call(Qual("time", "Minute"))).Block(
+			Id("limitersMu").Dot("Lock").Call(),
+			For(List(Id("ip"), Id("l")).Op(":=").Range().Id("limiters")).Block(
+				If(Id("l").Dot("Tokens").Call().Op(">=").Id("limitBurst")).Block(
+					Delete(Id("limiters"), Id("ip")),
+				),
+			),
+			Id("limitersMu").Dot("Unlock").Call(),
+		),
+	)
+}
+
+// generateServer generates the form server: routes for serving the form and receiving its responses, persisting
+// the responses to disk and basic auth. it lives in the same package as the form model, so that the package is
+// all that is needed to run a form
+func generateServer(packageName string, server serverOptions) *File {
+	s := NewFile(packageName)
+	s.Anon("embed")
+
+	s.Comment("//go:embed index-template.html")
+	s.Var().Id("htmlContents").String()
+	s.Comment("//go:embed response-template.html")
+	s.Var().Id("responseContents").String()
+	// the form in every language it's translated to, picked by formContents
+	formContents := Id("htmlContents")
+	if len(server.langs) > 0 {
+		translations := Dict{}
+		if server.lang != "" {
+			translations[Lit(server.lang)] = Id("htmlContents")
+		}
+		for _, lang := range server.langs {
+			name := "htmlContents" + identifier(strings.ReplaceAll(lang, "-", " "))
+			s.Comment(fmt.Sprintf("//go:embed index-template.%s.html", lang))
+			s.Var().Id(name).String()
+			if lang != server.lang {
+				translations[Lit(lang)] = Id(name)
+			}
+		}
+		s.Comment("the form's templates by language")
+		s.Var().Id("translations").Op("=").Map(String()).String().Values(translations)
+
+		s.Comment("formContents picks the template of the language asked for with ?lang=, else of the first language in the")
+		s.Comment("Accept-Language header that the form is translated to (de-AT falls back to de), else the default one")
+		s.Func().Id("formContents").Params(Id("req").Op("*").Qual("net/http", "Request")).String().Block(
+			If(List(Id("contents"), Id("ok")).Op(":=").Id("translations").Index(Id("req").Dot("URL").Dot("Query").Call().Dot("Get").Call(Lit("lang"))), Id("ok")).Block(
+				Return(Id("contents")),
+			),
+			For(List(Id("_"), Id("tag")).Op(":=").Range().Qual("strings", "Split").Call(Id("req").Dot("Header").Dot("Get").Call(Lit("Accept-Language")), Lit(","))).Block(
+				Id("tag").Op("=").Qual("strings", "TrimSpace").Call(Qual("strings", "Split").Call(Id("tag"), Lit(";")).Index(Lit(0))),
+				If(List(Id("contents"), Id("ok")).Op(":=").Id("translations").Index(Id("tag")), Id("ok")).Block(
+					Return(Id("contents")),
+				),
+				If(List(Id("contents"), Id("ok")).Op(":=").Id("translations").Index(Qual("strings", "Split").Call(Id("tag"), Lit("-")).Index(Lit(0))), Id("ok")).Block(
+					Return(Id("contents")),
+				),
+			),
+			Return(Id("htmlContents")),
+		)
+		formContents = Id("formContents").Call(Id("req"))
+	}
+
+	s.Comment("answers are kept as generic json values, as not every answer is a plain string (e.g. checkboxes are lists)")
+	s.Var().Id("responses").Map(String()).Map(String()).Interface()
+
+	s.Comment("used for generating a random identifier")
+	s.Const().Id("characterSet").Op("=").Lit("abcdedfghijklmnopqrstABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	s.Const().Id("pwlength").Op("=").Lit(20)
+
+	s.Func().Id("generateResponseIdentifier").Params().String().Block(
+		Var().Id("identifier").Qual("strings", "Builder"),
+		Const().Id("maxChar").Op("=").Int64().Call(Len(Id("characterSet"))),
+		For(Id("i").Op(":=").Lit(0), Id("i").Op("<").Id("pwlength"), Id("i").Op("++")).Block(
+			Id("max").Op(":=").Qual("math/big", "NewInt").Call(Id("maxChar")),
+			List(Id("bigN"), Err()).Op(":=").Qual("crypto/rand", "Int").Call(Qual("crypto/rand", "Reader"), Id("max")),
+			If(Err().Op("!=").Nil()).Block(
+				Qual("fmt", "Println").Call(Lit("crand.Int err"), Err()),
+			),
+			Id("n").Op(":=").Id("bigN").Dot("Int64").Call(),
+			Id("identifier").Dot("WriteByte").Call(Id("characterSet").Index(Id("n"))),
+		),
+		Return(Id("identifier").Dot("String").Call()),
+	)
+
+	s.Func().Id("throwBasicAuthHeader").Params(Id("res").Qual("net/http", "ResponseWriter")).Block(
+		Id("res").Dot("Header").Call().Dot("Set").Call(Lit("WWW-Authenticate"), Lit(`Basic realm="restricted", charset="UTF-8"`)),
+		Qual("net/http", "Error").Call(Id("res"), Lit("Unauthorized"), Qual("net/http", "StatusUnauthorized")),
+	)
+
+	s.Comment("checkBasicAuth reports whether the request may proceed, throwing the basic auth header if not")
+	s.Func().Id("checkBasicAuth").Params(
+		Id("res").Qual("net/http", "ResponseWriter"),
+		Id("req").Op("*").Qual("net/http", "Request"),
+	).Bool().Block(
+		If(Id("BasicPasswordHash").Op("==").Lit("")).Block(Return(True())),
+		List(Id("uname"), Id("pw"), Id("ok")).Op(":=").Id("req").Dot("BasicAuth").Call(),
+		If(Op("!").Id("ok").Op("||").Id("uname").Op("!=").Id("BasicUser").Op("||").Qual("golang.org/x/crypto/bcrypt", "CompareHashAndPassword").Call(
+			Index().Byte().Call(Id("BasicPasswordHash")), Index().Byte().Call(Id("pw")),
+		).Op("!=").Nil()).Block(
+			Id("throwBasicAuthHeader").Call(Id("res")),
+			Return(False()),
+		),
+		Return(True()),
+	)
+
+	errProcessing := Lit("error processing your response, it has not been persisted - sorry! contact admin")
+	redirect := Qual("net/http", "Redirect").Call(Id("res"), Id("req"), Lit("/responder/").Op("+").Id("id"), Qual("net/http", "StatusFound"))
+	if server.redirectPath != "" {
+		// a 303 makes the browser GET the redirect path, so that refreshing it doesn't submit the form again
+		redirect = Qual("net/http", "Redirect").Call(Id("res"), Id("req"), Lit(server.redirectPath+"?id=").Op("+").Id("id"), Qual("net/http", "StatusSeeOther"))
+	}
+	honeypotFilled := Null()
+	if server.honeypot != "" {
+		// only bots fill in the hidden honeypot field. they're shown an empty response page, as if all went well, so
+		// that they don't try again
+		honeypotFilled = If(Id("req").Dot("PostFormValue").Call(Lit(server.honeypot)).Op("!=").Lit("")).Block(
+			Qual("fmt", "Println").Call(Lit("dropped a response with the honeypot filled in")),
+			Id("t").Op(":=").Qual("html/template", "Must").Call(Qual("html/template", "New").Call(Lit("")).Dot("Parse").Call(Id("responseContents"))),
+			Id("t").Dot("Execute").Call(Id("res"), Id("ResponderData").Values(Dict{Id("Data"): Lit("{}")})),
+			Return(),
+		)
+	}
+	rateLimited := Null()
+	if server.rateLimit > 0 {
+		rateLimited = If(Op("!").Id("allowResponse").Call(Id("req"))).Block(
+			Qual("net/http", "Error").Call(Id("res"), Lit("too many responses, try again later"), Qual("net/http", "StatusTooManyRequests")),
+			Return(),
+		)
+		generateRateLimiter(s, server.rateLimit, server.ratePeriod)
+	}
+	// csrf protection by double submit: the form carries the same random token as a cookie only this site can set,
+	// which a form on some other site posting here can't know
+	s.Const().Id("csrfCookie").Op("=").Lit("csrf_token")
+	s.Comment("csrfToken returns the csrf token of the client, setting a new one if it doesn't have one yet. the token is kept")
+	s.Comment("between page loads, so that having the form open in several tabs works")
+	s.Func().Id("csrfToken").Params(
+		Id("res").Qual("net/http", "ResponseWriter"),
+		Id("req").Op("*").Qual("net/http", "Request"),
+	).String().Block(
+		If(List(Id("cookie"), Err()).Op(":=").Id("req").Dot("Cookie").Call(Id("csrfCookie")), Err().Op("==").Nil().Op("&&").Len(Id("cookie").Dot("Value")).Op("==").Id("pwlength")).Block(
+			Return(Id("cookie").Dot("Value")),
+		),
+		Id("token").Op(":=").Id("generateResponseIdentifier").Call(),
+		Qual("net/http", "SetCookie").Call(Id("res"), Op("&").Qual("net/http", "Cookie").Values(Dict{
+			Id("Name"):     Id("csrfCookie"),
+			Id("Value"):    Id("token"),
+			Id("Path"):     Lit("/"),
+			Id("HttpOnly"): True(),
+			Id("SameSite"): Qual("net/http", "SameSiteStrictMode"),
+		})),
+		Return(Id("token")),
+	)
+	s.Comment("checkCSRF reports whether the posted csrf token matches the client's cookie")
+	s.Func().Id("checkCSRF").Params(Id("req").Op("*").Qual("net/http", "Request")).Bool().Block(
+		List(Id("cookie"), Err()).Op(":=").Id("req").Dot("Cookie").Call(Id("csrfCookie")),
+		If(Err().Op("!=").Nil().Op("||").Id("cookie").Dot("Value").Op("==").Lit("")).Block(Return(False())),
+		Id("posted").Op(":=").Id("req").Dot("PostFormValue").Call(Id("csrfCookie")),
+		Return(Qual("crypto/subtle", "ConstantTimeCompare").Call(Index().Byte().Call(Id("posted")), Index().Byte().Call(Id("cookie").Dot("Value"))).Op("==").Lit(1)),
+	)
+
+	s.Comment("formPage is what the form's template is rendered with. Values and Errors are only set when showing the form")
+	s.Comment("again after a mistake in the answer, so that what was filled in isn't lost")
+	s.Type().Id("formPage").Struct(
+		Id("CSRFToken").String(),
+		Id("Values").Qual("net/url", "Values"),
+		Id("Errors").Map(String()).String(),
+	)
+	s.Comment("Value is the posted answer to the field with key")
+	s.Func().Params(Id("page").Id("formPage")).Id("Value").Params(Id("key").String()).String().Block(
+		Return(Id("page").Dot("Values").Dot("Get").Call(Id("key"))),
+	)
+	s.Comment("Checked reports whether value was picked for the field with key")
+	s.Func().Params(Id("page").Id("formPage")).Id("Checked").Params(Id("key"), Id("value").String()).Bool().Block(
+		For(List(Id("_"), Id("v")).Op(":=").Range().Id("page").Dot("Values").Index(Id("key"))).Block(
+			If(Id("v").Op("==").Id("value")).Block(Return(True())),
+		),
+		Return(False()),
+	)
+	s.Comment("Error is what's wrong with the answer to the field with key")
+	s.Func().Params(Id("page").Id("formPage")).Id("Error").Params(Id("key").String()).String().Block(
+		Return(Id("page").Dot("Errors").Index(Id("key"))),
+	)
+
+	s.Comment("renderForm shows the form. after mistakes in the answer, errs says what's wrong, and the form is filled in")
+	s.Comment("with the posted answers. only the first mistake in every field is shown")
+	s.Func().Id("renderForm").Params(
+		Id("res").Qual("net/http", "ResponseWriter"),
+		Id("req").Op("*").Qual("net/http", "Request"),
+		Id("errs").Index().Id("FieldError"),
+	).Block(
+		Id("page").Op(":=").Id("formPage").Values(Dict{Id("CSRFToken"): Id("csrfToken").Call(Id("res"), Id("req"))}),
+		If(Len(Id("errs")).Op(">").Lit(0)).Block(
+			Id("page").Dot("Values").Op("=").Id("req").Dot("PostForm"),
+			Id("page").Dot("Errors").Op("=").Make(Map(String()).String()),
+			For(List(Id("_"), Id("err")).Op(":=").Range().Id("errs")).Block(
+				If(List(Id("_"), Id("ok")).Op(":=").Id("page").Dot("Errors").Index(Id("err").Dot("Key")), Op("!").Id("ok")).Block(
+					Id("page").Dot("Errors").Index(Id("err").Dot("Key")).Op("=").Id("err").Dot("Message"),
+				),
+			),
+			Id("res").Dot("WriteHeader").Call(Qual("net/http", "StatusBadRequest")),
+		),
+		Id("t").Op(":=").Qual("html/template", "Must").Call(Qual("html/template", "New").Call(Lit("")).Dot("Parse").Call(formContents)),
+		If(Err().Op(":=").Id("t").Dot("Execute").Call(Id("res"), Id("page")), Err().Op("!=").Nil()).Block(
+			Qual("fmt", "Println").Call(Lit("err rendering form"), Err()),
+		),
+	)
+
+	s.Func().Id("indexRoute").Params(
+		Id("res").Qual("net/http", "ResponseWriter"),
+		Id("req").Op("*").Qual("net/http", "Request"),
+	).Block(
+		If(Op("!").Id("checkBasicAuth").Call(Id("res"), Id("req"))).Block(Return()),
+		Switch(Id("req").Dot("Method")).Block(
+			Case(Qual("net/http", "MethodPost")).Block(
+				rateLimited,
+				Comment("the body is read before anything looks at it, so that one that's too large is rejected as such"),
+				Id("req").Dot("Body").Op("=").Qual("net/http", "MaxBytesReader").Call(Id("res"), Id("req").Dot("Body"), Id("MaxBodySize")),
+				If(Err().Op(":=").Id("parseRequest").Call(Id("req")), Err().Op("!=").Nil()).Block(
+					Qual("fmt", "Println").Call(Lit("malformed response"), Err()),
+					Var().Id("tooLarge").Op("*").Qual("net/http", "MaxBytesError"),
+					If(Qual("errors", "As").Call(Err(), Op("&").Id("tooLarge"))).Block(
+						Qual("net/http", "Error").Call(Id("res"), Lit("the response is too large"), Qual("net/http", "StatusRequestEntityTooLarge")),
+						Return(),
+					),
+					Qual("net/http", "Error").Call(Id("res"), Err().Dot("Error").Call(), Qual("net/http", "StatusBadRequest")),
+					Return(),
+				),
+				If(Op("!").Id("checkCSRF").Call(Id("req"))).Block(
+					Qual("fmt", "Println").Call(Lit("rejected a POST without a valid csrf token")),
+					Qual("net/http", "Error").Call(Id("res"), Lit("the form has expired, reload it and try again"), Qual("net/http", "StatusForbidden")),
+					Return(),
+				),
+				honeypotFilled,
+				Id("answer").Op(":=").Id("FormAnswer").Values(),
+				Qual("fmt", "Println").Call(Lit("received a POST")),
+				Comment("a mistake in one of the answers shows the form again, with what's wrong with it"),
+				Var().Id("fieldErr").Op("*").Id("FieldError"),
+				If(Err().Op(":=").Id("answer").Dot("ParsePost").Call(Id("req")), Qual("errors", "As").Call(Err(), Op("&").Id("fieldErr"))).Block(
+					Qual("fmt", "Println").Call(Lit("invalid response"), Err()),
+					Id("renderForm").Call(Id("res"), Id("req"), Index().Id("FieldError").Values(Op("*").Id("fieldErr"))),
+					Return(),
+				).Else().If(Err().Op("!=").Nil()).Block(
+					Qual("fmt", "Println").Call(Lit("malformed response"), Err()),
+					Qual("net/http", "Error").Call(Id("res"), Err().Dot("Error").Call(), Qual("net/http", "StatusBadRequest")),
+					Return(),
+				),
+				If(Id("errs").Op(":=").Id("answer").Dot("Validate").Call(), Len(Id("errs")).Op(">").Lit(0)).Block(
+					Qual("fmt", "Println").Call(Lit("invalid response"), Id("errs")),
+					Id("renderForm").Call(Id("res"), Id("req"), Id("errs")),
+					Return(),
+				),
+				Comment("marshal the answer and then unmarshal it into a map, which is what gets persisted: this gives"),
+				Comment("a nice json representation on disk that can easily be manipulated with e.g. jq or little scripts."),
+				Comment("sensitive answers, like passwords, are redacted, so that they're neither stored nor shown"),
+				List(Id("b"), Err()).Op(":=").Qual("encoding/json", "Marshal").Call(Id("answer").Dot("Redacted").Call()),
+				If(Err().Op("!=").Nil()).Block(
+					Qual("fmt", "Println").Call(Lit("marshal err"), Err()),
+					Qual("fmt", "Fprint").Call(Id("res"), errProcessing),
+					Return(),
+				),
+				Var().Id("m").Map(String()).Interface(),
+				If(Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("b"), Op("&").Id("m")), Err().Op("!=").Nil()).Block(
+					Qual("fmt", "Println").Call(Lit("err when doing unmarshalling trick"), Err()),
+					Qual("fmt", "Fprint").Call(Id("res"), errProcessing),
+					Return(),
+				),
+				Id("id").Op(":=").Id("generateResponseIdentifier").Call(),
+				If(Err().Op(":=").Id("answer").Dot("SaveUploads").Call(Qual("path/filepath", "Join").Call(Lit("uploads"), Id("id"))), Err().Op("!=").Nil()).Block(
+					Qual("fmt", "Println").Call(Lit("error saving uploads"), Err()),
+					Qual("fmt", "Fprint").Call(Id("res"), errProcessing),
+					Return(),
+				),
+				Id("responses").Index(Id("id")).Op("=").Id("m"),
+				Id("persistData").Call(),
+				Comment("every answer is also appended to a json lines file, as a log of all submissions"),
+				If(Err().Op(":=").Id("answer").Dot("Save").Call(Id("answersName")), Err().Op("!=").Nil()).Block(
+					Qual("fmt", "Println").Call(Lit("error saving answer"), Err()),
+				),
+				Comment("redirect to response page"),
+				redirect,
+			),
+			Case(Qual("net/http", "MethodGet")).Block(
+				Id("renderForm").Call(Id("res"), Id("req"), Nil()),
+			),
+		),
+	)
+
+	s.Func().Id("responderRoute").Params(
+		Id("res").Qual("net/http", "ResponseWriter"),
+		Id("req").Op("*").Qual("net/http", "Request"),
+	).Block(
+		Id("renderResponse").Call(Id("res"), Qual("strings", "TrimPrefix").Call(Id("req").Dot("URL").Dot("Path"), Lit("/responder/"))),
+	)
+
+	if server.redirectPath != "" {
+		s.Comment("redirectRoute shows the response page at the form-redirect path, for the response with the id in the query")
+		s.Func().Id("redirectRoute").Params(
+			Id("res").Qual("net/http", "ResponseWriter"),
+			Id("req").Op("*").Qual("net/http", "Request"),
+		).Block(
+			If(Id("req").Dot("Method").Op("!=").Qual("net/http", "MethodGet")).Block(
+				Qual("net/http", "Error").Call(Id("res"), Lit("method not allowed"), Qual("net/http", "StatusMethodNotAllowed")),
+				Return(),
+			),
+			Id("renderResponse").Call(Id("res"), Id("req").Dot("URL").Dot("Query").Call().Dot("Get").Call(Lit("id"))),
+		)
+	}
+
+	s.Comment("renderResponse renders the response page for the response with the given id")
+	s.Func().Id("renderResponse").Params(
+		Id("res").Qual("net/http", "ResponseWriter"),
+		Id("id").String(),
+	).Block(
+		Comment("re-read the on-disk data in case it has been hand-edited (e.g. to update a \"processed\" flag, signaling"),
+		Comment("to the form responder that their order has now been processed)"),
+		Id("readPersistedData").Call(),
+		List(Id("val"), Id("ok")).Op(":=").Id("responses").Index(Id("id")),
+		If(Op("!").Id("ok")).Block(
+			Qual("fmt", "Fprint").Call(Id("res"), Lit("No such form responder id")),
+			Return(),
+		),
+		List(Id("niceJSON"), Err()).Op(":=").Qual("encoding/json", "MarshalIndent").Call(Id("val"), Lit(""), Lit("  ")),
+		If(Err().Op("!=").Nil()).Block(
+			Qual("fmt", "Printf").Call(Lit("err marshalling stored value for id %s\n"), Id("id")),
+			Qual("fmt", "Fprint").Call(Id("res"), Lit("Had an error when formatting your stored response for web purposes. Contact admin")),
+			Return(),
+		),
+		Id("t").Op(":=").Qual("html/template", "Must").Call(Qual("html/template", "New").Call(Lit("")).Dot("Parse").Call(Id("responseContents"))),
+		Err().Op("=").Id("t").Dot("Execute").Call(Id("res"), Id("ResponderData").Values(Dict{Id("Data"): String().Call(Id("niceJSON"))})),
+		If(Qual("errors", "Is").Call(Err(), Qual("syscall", "EPIPE"))).Block(
+			Qual("fmt", "Println").Call(Lit("recovering from broken pipe")),
+		).Else().If(Err().Op("!=").Nil()).Block(
+			Qual("fmt", "Println").Call(Lit("err rendering reponder view"), Err()),
+		),
+	)
+
+	s.Const().Id("dataName").Op("=").Lit("latest-form-data.json")
+	s.Const().Id("answersName").Op("=").Lit("answers.jsonl")
+	s.Func().Id("persistData").Params().Block(
+		List(Id("b"), Err()).Op(":=").Qual("encoding/json", "MarshalIndent").Call(Id("responses"), Lit(""), Lit("  ")),
+		If(Err().Op("!=").Nil()).Block(
+			Qual("fmt", "Println").Call(Lit("failure persisting data"), Err()),
+			Return(),
+		),
+		If(Err().Op(":=").Qual("os", "WriteFile").Call(Id("dataName"), Id("b"), Op("0777")), Err().Op("!=").Nil()).Block(
+			Qual("fmt", "Println").Call(Lit("error writing persisted form data"), Err()),
+		),
+	)
+
+	s.Func().Id("readPersistedData").Params().Block(
+		List(Id("data"), Err()).Op(":=").Qual("os", "ReadFile").Call(Id("dataName")),
+		If(Qual("errors", "Is").Call(Err(), Qual("os", "ErrNotExist"))).Block(
+			Comment("no data yet probably, it's fine let's just return"),
+			Return(),
+		),
+		If(Err().Op("!=").Nil()).Block(
+			Qual("fmt", "Println").Call(Lit("error reading persisted form data"), Err()),
+			Return(),
+		),
+		If(Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("data"), Op("&").Id("responses")), Err().Op("!=").Nil()).Block(
+			Qual("fmt", "Println").Call(Lit("error unmarshalling persisted form data"), Err()),
+		),
+	)
+
+	s.Comment("exportRoute exports all stored answers as csv, to whoever knows the form's password")
+	s.Func().Id("exportRoute").Params(
+		Id("res").Qual("net/http", "ResponseWriter"),
+		Id("req").Op("*").Qual("net/http", "Request"),
+	).Block(
+		Comment("without a form-password anyone could download every answer, so there's no export at all"),
+		If(Id("BasicPasswordHash").Op("==").Lit("")).Block(
+			Qual("net/http", "NotFound").Call(Id("res"), Id("req")),
+			Return(),
+		),
+		If(Op("!").Id("checkBasicAuth").Call(Id("res"), Id("req"))).Block(Return()),
+		Id("readPersistedData").Call(),
+		Comment("sort the response ids, so that exports of the same data are identical"),
+		Var().Id("ids").Index().String(),
+		For(Id("id").Op(":=").Range().Id("responses")).Block(
+			Id("ids").Op("=").Append(Id("ids"), Id("id")),
+		),
+		Qual("sort", "Strings").Call(Id("ids")),
+		Id("res").Dot("Header").Call().Dot("Set").Call(Lit("Content-Type"), Lit("text/csv")),
+		Id("w").Op(":=").Qual("encoding/csv", "NewWriter").Call(Id("res")),
+		Id("w").Dot("Write").Call(Append(Index().String().Values(Lit("id")), Id("FormAnswerCSVHeader").Call().Op("..."))),
+		For(List(Id("_"), Id("id")).Op(":=").Range().Id("ids")).Block(
+			Comment("the stored answers are generic json, so they are read back into a FormAnswer by way of json"),
+			Var().Id("answer").Id("FormAnswer"),
+			List(Id("b"), Err()).Op(":=").Qual("encoding/json", "Marshal").Call(Id("responses").Index(Id("id"))),
+			If(Err().Op("==").Nil()).Block(
+				Err().Op("=").Qual("encoding/json", "Unmarshal").Call(Id("b"), Op("&").Id("answer")),
+			),
+			If(Err().Op("!=").Nil()).Block(
+				Qual("fmt", "Println").Call(Lit("err exporting response"), Id("id"), Err()),
+				Continue(),
+			),
+			Id("w").Dot("Write").Call(Append(Index().String().Values(Id("id")), Id("answer").Dot("CSVRecord").Call().Op("..."))),
+		),
+		Id("w").Dot("Flush").Call(),
+	)
+
+	s.Comment("Handler returns the form's routes, for serving the form from your own http.Server")
+	handler := []Code{
+		Id("responses").Op("=").Make(Map(String()).Map(String()).Interface()),
+		Id("readPersistedData").Call(),
+		Id("mux").Op(":=").Qual("net/http", "NewServeMux").Call(),
+		Id("mux").Dot("HandleFunc").Call(Lit("/responder/"), Id("responderRoute")),
+		Id("mux").Dot("HandleFunc").Call(Lit("/export.csv"), Id("exportRoute")),
+		Id("mux").Dot("HandleFunc").Call(Lit("/"), Id("indexRoute")),
+	}
+	if server.redirectPath != "" {
+		handler = append(handler, Id("mux").Dot("HandleFunc").Call(Lit(server.redirectPath), Id("redirectRoute")))
+	}
+	if server.rateLimit > 0 {
+		handler = append(handler, Id("forgetting").Dot("Do").Call(Func().Params().Block(Go().Id("forgetLimiters").Call())))
+	}
+	handler = append(handler, Return(Id("mux")))
+	s.Func().Id("Handler").Params().Qual("net/http", "Handler").Block(handler...)
+
+	s.Comment("Serve serves the form on the given port")
+	s.Func().Id("Serve").Params(Id("port").Int()).Error().Block(
+		Id("server").Op(":=").Op("&").Qual("net/http", "Server").Values(Dict{
+			Id("Addr"):    Qual("fmt", "Sprintf").Call(Lit(":%d"), Id("port")),
+			Id("Handler"): Id("Handler").Call(),
+		}),
+		Qual("fmt", "Println").Call(Lit("Listening on port: "), Id("server").Dot("Addr")),
+		Return(Id("server").Dot("ListenAndServe").Call()),
+	)
+	return s
+}
+
+// generateCommand generates the main package of a command serving the form package at importPath on its own, which
+// shuts down gracefully on ctrl-c: responses that are being handled are finished first
+func generateCommand(packageName, importPath string) *File {
+	c := NewFile("main")
+	c.Comment(fmt.Sprintf("the command serving the %s form. the routes themselves are in the %s package, so this only", packageName, packageName))
+	c.Comment("runs them")
+	c.Func().Id("main").Params().Block(
+		Var().Id("port").Int(),
+		Qual("flag", "IntVar").Call(Op("&").Id("port"), Lit("port"), Lit(7272), Lit("the port to serve the form server on")),
+		Qual("flag", "Parse").Call(),
+		Id("server").Op(":=").Op("&").Qual("net/http", "Server").Values(Dict{
+			Id("Addr"):    Qual("fmt", "Sprintf").Call(Lit(":%d"), Id("port")),
+			Id("Handler"): Qual(importPath, "Handler").Call(),
+		}),
+		Comment("stop accepting responses on ctrl-c, and give the ones in flight some time to finish"),
+		Id("stopped").Op(":=").Make(Chan().Struct()),
+		Go().Func().Params().Block(
+			Id("interrupt").Op(":=").Make(Chan().Qual("os", "Signal"), Lit(1)),
+			Qual("os/signal", "Notify").Call(Id("interrupt"), Qual("os", "Interrupt"), Qual("syscall", "SIGTERM")),
+			Op("<-").Id("interrupt"),
+			Qual("fmt", "Println").Call(Lit("shutting down")),
+			List(Id("ctx"), Id("cancel")).Op(":=").Qual("context", "WithTimeout").Call(Qual("context", "Background").Call(), Lit(10).Op("*").Qual("time", "Second")),
+			Defer().Id("cancel").Call(),
+			If(Err().Op(":=").Id("server").Dot("Shutdown").Call(Id("ctx")), Err().Op("!=").Nil()).Block(
+				Qual("fmt", "Println").Call(Lit("error shutting down"), Err()),
+			),
+			Close(Id("stopped")),
+		).Call(),
+		Qual("fmt", "Println").Call(Lit("Listening on port: "), Id("server").Dot("Addr")),
+		If(Err().Op(":=").Id("server").Dot("ListenAndServe").Call(), Op("!").Qual("errors", "Is").Call(Err(), Qual("net/http", "ErrServerClosed"))).Block(
+			Qual("fmt", "Println").Call(Lit("form server stopped"), Err()),
+			Qual("os", "Exit").Call(Lit(1)),
+		),
+		Op("<-").Id("stopped"),
+	)
+	return c
+}
+
+// moduleImportPath finds the go.mod that dir is part of, and returns the import path of the package in dir
+func moduleImportPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	var rel []string
+	for root := dir; ; root = filepath.Dir(root) {
+		if b, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(b), "\n") {
+				if line = strings.TrimSpace(line); strings.HasPrefix(line, "module ") {
+					module := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+					return strings.Join(append([]string{module}, rel...), "/"), nil
+				}
+			}
+			return "", fmt.Errorf("%s has no module line", filepath.Join(root, "go.mod"))
+		}
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("%s isn't part of a go module, there's no go.mod in it or above it", dir)
+		}
+		rel = append([]string{filepath.Base(root)}, rel...)
+	}
+}
+
+const formPackageName = "myform"
+
+// generateOptions are the flags that generating a form package depends on
+type generateOptions struct {
+	formatFp string
+	// the format read from standard input, with `--input -`. read once up front, as it's generated from more than
+	// once with --lang
+	stdin []byte
+	outputDir, packageName string
+	stylesheetFp string
+	headerFp, footerFp string
+	// don't print the generated form model, e.g. when regenerating with --watch
+	quiet bool
+	// where the generated files are written to
+	out writer
+	// fail on unknown elements, rather than warning about them
+	strict bool
+	// number fields with duplicate keys, rather than failing
+	dedupeSuffix bool
+	// also write a json schema of the answers to schema.json
+	schema bool
+	// also write an openapi spec of the form server to openapi.yaml
+	openAPI bool
+	// sort the fields of the answer by key, rather than keeping them in the order of the form
+	sortFields bool
+	// also write a command serving the form package to cmd/<package>/main.go, inside the package
+	withServer bool
+	// the input is a yaml form definition, rather than a format file
+	yaml bool
+	// the languages to also write a translated index-template.<lang>.html for, set with --lang
+	langs []string
+	// the language the form is generated in, when generating one of the translated templates. translated templates
+	// share the go code generated for the default language, so only the template itself is written
+	lang string
+}
+
+// langFlag collects the languages passed with (repeated) --lang flags
+type langFlag []string
+
+func (langs *langFlag) String() string {
+	return strings.Join(*langs, ",")
+}
+
+func (langs *langFlag) Set(lang string) error {
+	if !langPattern.MatchString(lang) {
+		return fmt.Errorf("%q is not a language tag, expected e.g. de or pt-BR", lang)
+	}
+	*langs = append(*langs, lang)
+	return nil
+}
+
+// writer writes the generated files, which is either to disk or, with --dry-run, to stdout
+type writer interface {
+	MkdirAll(dir string) error
+	WriteFile(fp string, b []byte) error
+}
+
+type diskWriter struct{}
+
+func (diskWriter) MkdirAll(dir string) error {
+	return os.MkdirAll(dir, 0777)
+}
+
+func (diskWriter) WriteFile(fp string, b []byte) error {
+	return os.WriteFile(fp, b, 0777)
+}
+
+// dryRunWriter prints the files that would have been written, separated by their paths
+type dryRunWriter struct{}
+
+func (dryRunWriter) MkdirAll(dir string) error {
+	return nil
+}
+
+func (dryRunWriter) WriteFile(fp string, b []byte) error {
+	fmt.Printf("==> %s <==\n%s\n", fp, b)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// run generates the form package as configured by the flags, returning whatever went wrong on the way
+func run() error {
+	var opts generateOptions
+	var watch, dryRun bool
+	var fromSchemaFp string
+	var inputFormat string
+	flag.StringVar(&opts.headerFp, "html-header", "", "a single html file containing all of the html that will be presented immediately above the form contents")
+	flag.StringVar(&opts.footerFp, "html-footer", "", "a single html file containing all of the html that will be presented immediately below the form contents")
+	flag.StringVar(&opts.stylesheetFp, "stylesheet", "", "a single css file containing styles that will be applied to the form (fully replaces mould's default styling)")
+	flag.StringVar(&opts.formatFp, "input", "", "a file containing the form format to generate a form server using (- reads it from standard input)")
+	flag.StringVar(&opts.outputDir, "output", formPackageName, "the directory to write the generated form package to. its last path segment is used as the package name")
+	flag.StringVar(&opts.packageName, "package", "", "the package name of the generated form package (defaults to the last path segment of --output)")
+	flag.BoolVar(&watch, "watch", false, "keep running and regenerate the form package whenever the --input file changes")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the generated files instead of writing them to --output")
+	flag.BoolVar(&opts.withServer, "with-server", false, "also write a command serving the form, with graceful shutdown, to cmd/<package>/main.go in --output")
+	flag.BoolVar(&opts.sortFields, "sort-fields", false, "sort the fields of the generated answer (and so the stored json and csv columns) by key, instead of keeping the form's order")
+	flag.BoolVar(&opts.strict, "strict", false, "fail on unknown elements in the format file, instead of only warning about them")
+	flag.BoolVar(&opts.dedupeSuffix, "dedupe-suffix", false, "number fields that share a key (name, name2, name3, ...), instead of failing")
+	flag.BoolVar(&opts.schema, "schema", false, "also write a json schema (draft-07) describing the stored answers to schema.json")
+	flag.BoolVar(&opts.openAPI, "openapi", false, "also write an openapi 3 spec describing the form server's routes to openapi.yaml")
+	flag.StringVar(&inputFormat, "format", "", "the syntax of the --input file: mould or yaml (defaults to yaml for .yaml and .yml files, mould otherwise)")
+	flag.Var((*langFlag)(&opts.langs), "lang", "also write the form translated to this language, as index-template.<lang>.html (can be repeated)")
+	flag.StringVar(&fromSchemaFp, "from-schema", "", "print a form format for the json schema in the given file, as a starting point for a form")
+	flag.Parse()
+	opts.out = diskWriter{}
+	if dryRun {
+		opts.out = dryRunWriter{}
+		// the generated form model is printed with the other files
+		opts.quiet = true
+	}
+	if fromSchemaFp != "" {
+		b, err := os.ReadFile(fromSchemaFp)
+		if err != nil {
+			return err
+		}
+		format, err := formatFromSchema(b)
+		if err != nil {
+			return fmt.Errorf("--from-schema %s: %w", fromSchemaFp, err)
+		}
+		fmt.Print(format)
+		return nil
+	}
+	if opts.formatFp == "" {
+		return fmt.Errorf("must pass --input <file containing form format>")
+	}
+	switch inputFormat {
+	case "":
+		ext := strings.ToLower(filepath.Ext(opts.formatFp))
+		opts.yaml = ext == ".yaml" || ext == ".yml"
+	case "yaml":
+		opts.yaml = true
+	case "mould":
+	default:
+		return fmt.Errorf("--format %s: unknown input format, expected mould or yaml", inputFormat)
+	}
+	// the package name defaults to the last path segment of the output directory
+	if opts.packageName == "" {
+		opts.packageName = filepath.Base(filepath.Clean(opts.outputDir))
+		if !token.IsIdentifier(opts.packageName) {
+			return fmt.Errorf("--output %s: %q is not a valid go package name, either pass --package or make the last path segment of --output a go identifier (e.g. myform)", opts.outputDir, opts.packageName)
+		}
+	} else if !token.IsIdentifier(opts.packageName) {
+		return fmt.Errorf("--package %s: not a valid go package name, must be a go identifier (e.g. myform)", opts.packageName)
+	}
+	if opts.formatFp == "-" {
+		if watch {
+			return fmt.Errorf("--watch can't watch standard input, pass --input a file instead")
+		}
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("issue when reading format from standard input: %w", err)
+		}
+		opts.stdin = b
+	}
+	if watch {
+		watchFormat(opts)
+		return nil
+	}
+	_, _, err := generate(opts)
+	return err
+}
+
+// watchFormat regenerates the form package every time the format file is modified, until interrupted. errors in
+// the format are reported, and then it's back to waiting for the next change
+func watchFormat(opts generateOptions) {
+	opts.quiet = true
+	fmt.Printf("watching %s for changes, stop with ctrl-c\n", opts.formatFp)
+	var lastMod time.Time
+	for ; ; time.Sleep(500 * time.Millisecond) {
+		info, err := os.Stat(opts.formatFp)
+		if err != nil {
+			// editors often replace the file when saving, so it may briefly not exist
+			continue
+		}
+		if info.ModTime().Equal(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+		fieldCount, written, err := generate(opts)
+		if err != nil {
+			fmt.Printf("[%s] %s: %v\n", time.Now().Format("15:04:05"), opts.formatFp, err)
+			continue
+		}
+		fmt.Printf("[%s] generated %d fields, wrote %s\n", time.Now().Format("15:04:05"), fieldCount, strings.Join(written, ", "))
+	}
+}
+
+// generate parses the format file and writes the generated form package to opts.outputDir, returning the number of
+// answer fields and the files that were written
+func generate(opts generateOptions) (int, []string, error) {
+	var htmlList []string
+	var theme Theme
+	var setPassword string
+	setUser := "mouldy" // default user is "mouldy". only used if password is set, and can be changed with `form-user`
+	var pageTitle string
+	var server serverOptions
+	styleData := StyleData{Width: "600px"}
+	// the most a posted answer can take up, set with form-maxbody
+	var maxBody int64 = 10 << 20
+	// where the form is submitted to, and how. the generated server only handles posts to /, but the form can be
+	// pointed elsewhere, e.g. at a third-party form backend
+	formAction, formMethod := "/", "post"
+	// where form-method was set, for mistakes found once the whole format is read
+	methodPosition := ""
+	responseData := ResponseData{Title: defaultResponseTitle, Message: defaultResponseMessage}
+	var written []string
+	b := opts.stdin
+	var err error
+	if opts.formatFp != "-" {
+		b, err = os.ReadFile(opts.formatFp)
+		if err != nil {
+			return 0, nil, fmt.Errorf("issue when reading format file: %w", err)
+		}
+	}
+	format := string(b)
+
+	var values []genValue
+	var errs []error
+	if opts.yaml {
+		values, errs = parseYAMLFormat(format, opts.strict)
+	} else {
+		values, errs = parseFormat(format, "", opts.strict)
+	}
+	if len(errs) == 0 {
+		values, errs = spliceIncludes(values, filepath.Dir(opts.formatFp), []string{opts.formatFp}, opts.strict)
+	}
+	if len(errs) == 0 {
+		values, errs = localize(values, opts.lang)
+	}
+	if len(errs) > 0 {
+		return 0, nil, parseErrors(errs)
+	}
+
+	f := NewFile(opts.packageName)
+	var contentBits []Code
+	var fields []answerField
+	var resParse []Code
+	// server-side checks that html attributes can't express, generated into FormAnswer.Validate()
+	var validation []Code
+	// set when the form contains file uploads, which need req.ParseMultipartForm() instead
+	var multipart bool
+	// generated into FormAnswer.SaveUploads(), writing uploaded files to disk
+	var uploads []Code
+	// set when a hidden field is filled with a generated uuid
+	var autoUUID bool
+	// set when a rank element needs parseRanking()
+	var ranking bool
+	// set when a money element needs parseCents()
+	var money bool
+	// set when a number, amount of money or yes/no question is answered, which ParsePost() keeps track of for Validate(),
+	// as an unanswered one can't be told apart from a zero or a no
+	var tracked bool
+	// set once the pattern for checking color answers has been added to patterns
+	var hexColor bool
+	// set once the pattern for checking email answers has been added to patterns
+	var emailCheck bool
+	// struct types of repeated entries, declared alongside FormAnswer
+	var types []Code
+	// package level regexps of fields validated with a pattern, compiled once rather than on every response
+	var patterns []Code
+	// constants for the options of radios and selects, declared alongside FormAnswer, and the names they take up.
+	// the package's own exported names are taken from the start, so that `radio[Field] = Error` doesn't redeclare
+	// FieldError
+	var enums []Code
+	enumNames := make(map[string]bool)
+	for _, name := range []string{"BasicPasswordHash", "BasicUser", "FieldError", "FieldInfo", "Fields", "FormAnswer", "FormAnswerCSVHeader", "FormContent", "Handler", "MaxBodySize", "ResponderData", "Serve"} {
+		enumNames[name] = true
+	}
+	// the number of section[...] fieldsets that haven't been closed by an end-section yet
+	var openSections int
+	// set when a field is only shown depending on another's answer, which needs conditionScript
+	var conditions bool
+	// set while a form-section fieldset is open, which is closed by the next form-section or the end of the form
+	var formSectionOpen bool
 	for _, input := range values {
+		switch input.element {
+		case "form-title":
+			contentBits = append(contentBits, Id("Title").String())
+			htmlList = append(htmlList, fmt.Sprintf(`<h1>%s</h1>`, html.EscapeString(input.value)))
+			pageTitle = input.value
+		case "form-desc":
+			contentBits = append(contentBits, Id("Description").String())
+			htmlList = append(htmlList, fmt.Sprintf(`<p>%s</p>`, escapeParagraph(input.value)))
+		case "response-title":
+			responseData.Title = input.value
+		case "response-message":
+			responseData.Message = template.HTML(escapeParagraph(input.value))
+		case "form-image":
+			contentBits = append(contentBits, Id("Image").String())
+			htmlList = append(htmlList, fmt.Sprintf(`<img src="%s">`, html.EscapeString(input.value)))
+		case "form-password":
+			setPassword = input.value
+			// information used for basic auth, limiting access to the form
+			contentBits = append(contentBits, Id("Password").String())
+		case "form-lang":
+			if !langPattern.MatchString(input.value) {
+				return 0, nil, fmt.Errorf("%s: form-lang: %q is not a language tag, expected e.g. en or pt-BR", input.position(), input.value)
+			}
+			server.lang = input.value
+			responseData.Lang = input.value
+		case "form-redirect":
+			// only local paths, so that the form can't be used to send respondents off to some other site
+			if err := checkRedirectPath(input.value); err != nil {
+				return 0, nil, fmt.Errorf("%s: form-redirect: %w", input.position(), err)
+			}
+			server.redirectPath = input.value
+		case "form-honeypot":
+			server.honeypot = "website"
+			if input.value != "" {
+				server.honeypot = input.value
+			}
+			styleData.Honeypot = true
+		case "form-maxbody":
+			size, err := parseByteSize(input.value)
+			if err != nil || size < 1 {
+				return 0, nil, fmt.Errorf("%s: form-maxbody: %q is not a size, expected e.g. 10mb or 500kb", input.position(), input.value)
+			}
+			maxBody = size
+		case "form-ratelimit":
+			limit, period, err := parseRateLimit(input.value)
+			if err != nil {
+				return 0, nil, fmt.Errorf("%s: form-ratelimit: %w", input.position(), err)
+			}
+			server.rateLimit, server.ratePeriod = limit, period
+		case "form-action":
+			formAction = input.value
+		case "form-method":
+			formMethod, methodPosition = strings.ToLower(input.value), input.position()
+			if formMethod != "get" && formMethod != "post" {
+				return 0, nil, fmt.Errorf("%s: form-method: %q is not a method forms can use, expected GET or POST", input.position(), input.value)
+			}
+		case "form-user":
+			setUser = input.value
+			// information used for basic auth, limiting access to the form
+			contentBits = append(contentBits, Id("User").String())
+		case "form-bg":
+			theme.background = input.value
+		case "form-titlecolor":
+			theme.title = input.value
+		case "form-fg":
+			theme.body = input.value
+		case "form-bg-dark":
+			theme.backgroundDark = input.value
+		case "form-titlecolor-dark":
+			theme.titleDark = input.value
+		case "form-fg-dark":
+			theme.bodyDark = input.value
+		case "form-width":
+			if err := checkWidth(input.value); err != nil {
+				return 0, nil, fmt.Errorf("%s: form-width: %w", input.position(), err)
+			}
+			styleData.Width = input.value
+		case "form-font", "form-headingfont":
+			fontImport, font, err := parseFont(input.value)
+			if err != nil {
+				return 0, nil, fmt.Errorf("%s: %s: %w", input.position(), input.element, err)
+			}
+			if fontImport != "" {
+				theme.fontImports = append(theme.fontImports, fontImport)
+			}
+			if input.element == "form-font" {
+				theme.font = font
+			} else {
+				theme.headingFont = font
+			}
+		}
+	}
+
+	// file uploads are only posted by forms using the multipart encoding
+	enctype := ""
+	for _, input := range values {
+		if input.element == "file" {
+			enctype = ` enctype="multipart/form-data"`
+			multipart = true
+		}
+	}
+	if multipart && formMethod == "get" {
+		return 0, nil, fmt.Errorf("%s: form-method: file uploads can't be sent with GET, use POST", methodPosition)
+	}
+	// the form server only reads posted answers, so a form sent back to it with GET would lose every response
+	if formMethod == "get" && formAction == "/" {
+		return 0, nil, fmt.Errorf("%s: form-method: the form server only receives POST, set form-action to send GET somewhere else", methodPosition)
+	}
+	htmlList = append(htmlList, fmt.Sprintf(`<form action="%s" method="%s"%s>`, html.EscapeString(formAction), formMethod, enctype))
+	// filled in with the client's csrf token by the form server
+	htmlList = append(htmlList, fmt.Sprintf(`<input type="hidden" name="csrf_token" value="%s"/>`, csrfPlaceholder))
+	if server.honeypot != "" {
+		// a plain text field, which bots can't tell from the others, hidden from people by the stylesheet and skipped
+		// when tabbing through the form
+		htmlList = append(htmlList, `<div class="honeypot" aria-hidden="true">`)
+		htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">Leave this empty</label>`, html.EscapeString(server.honeypot)))
+		htmlList = append(htmlList, fmt.Sprintf(`<input type="text" id="%s" name="%s" tabindex="-1" autocomplete="off"/>`, html.EscapeString(server.honeypot), html.EscapeString(server.honeypot)))
+		htmlList = append(htmlList, "</div>")
+	}
+
+	// every field needs a key and title of its own: inputs sharing a name would clobber each other, and FormAnswer
+	// wouldn't compile with two fields of the same name. with --dedupe-suffix, the later ones are numbered instead
+	keyLines := make(map[string]string)
+	titleLines := make(map[string]string)
+	var duplicates parseErrors
+	for i, input := range values {
+		if !elements[input.element] || strings.HasPrefix(input.element, "form-") || strings.HasPrefix(input.element, "response-") {
+			continue
+		}
+		key, title := formatKeyAndTitle(input)
+		if title == "" {
+			duplicates = append(duplicates, fmt.Errorf("%s: can't name a field after %q, it needs at least one letter or digit", input.position(), input.title))
+			continue
+		}
+		baseKey := key
+		for n := 2; opts.dedupeSuffix && (keyLines[key] != "" || titleLines[title] != ""); n++ {
+			values[i].key = fmt.Sprintf("%s%d", baseKey, n)
+			key, title = formatKeyAndTitle(values[i])
+		}
+		if line, ok := keyLines[key]; ok {
+			duplicates = append(duplicates, fmt.Errorf("%s: the key %q is already used on %s", input.position(), key, line))
+			continue
+		}
+		if line, ok := titleLines[title]; ok {
+			duplicates = append(duplicates, fmt.Errorf("%s: the field name %s is already used on %s", input.position(), title, line))
+			continue
+		}
+		keyLines[key] = input.position()
+		titleLines[title] = input.position()
+	}
+	if line, ok := keyLines[server.honeypot]; ok {
+		duplicates = append(duplicates, fmt.Errorf("%s: the key %q is already used by form-honeypot, pick another name for either", line, server.honeypot))
+	}
+	if len(duplicates) > 0 {
+		return 0, nil, duplicates
+	}
+
+	for _, input := range values {
+			// where the html, checks and fields of this element start, for adding its help text, error messages and
+			// condition afterwards
+			elementStart := len(htmlList)
+			validationStart := len(validation)
+			fieldsStart := len(fields)
 			var required string 
 			if input.required {
-				required = `required`
+				// aria-required too, so that screen readers announce it regardless of how they treat `required`
+				required = `required aria-required="true"`
+			}
+		switch input.element {
+		case "textarea":
+			key, title := formatKeyAndTitle(input)
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			// `{maxlength=500}`, `{minwords=50}` and `{maxwords=100}` limit the length of the answer. maxlength is also
+			// enforced by the browser, but all of them are checked by the server, as the browser's check is easily
+			// bypassed. there's no html attribute for word counts at all
+			var maxlength, minwords, maxwords int
+			var limitAttribute string
+			var limits []string
+			for name, limit := range map[string]*int{"maxlength": &maxlength, "minwords": &minwords, "maxwords": &maxwords} {
+				value, ok := input.constraints[name]
+				if !ok {
+					continue
+				}
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return 0, nil, fmt.Errorf("%s: textarea[%s]: invalid %s %q, expected a positive number", input.position(), input.title, name, value)
+				}
+				*limit = n
+			}
+			if maxwords > 0 && minwords > maxwords {
+				return 0, nil, fmt.Errorf("%s: textarea[%s]: minwords %d is more than maxwords %d", input.position(), input.title, minwords, maxwords)
+			}
+			if maxlength > 0 {
+				limitAttribute = fmt.Sprintf(` maxlength="%d"`, maxlength)
+				limits = append(limits, fmt.Sprintf("at most %d characters", maxlength))
+			}
+			switch {
+			case minwords > 0 && maxwords > 0:
+				limits = append(limits, fmt.Sprintf("%d to %d words", minwords, maxwords))
+			case minwords > 0:
+				limits = append(limits, fmt.Sprintf("at least %d words", minwords))
+			case maxwords > 0:
+				limits = append(limits, fmt.Sprintf("at most %d words", maxwords))
+			}
+			placeholder, defaultValue := splitDefault(input)
+			el := fmt.Sprintf(`<textarea %s placeholder="%s" id="%s" name="%s"%s>%s</textarea>`, required, html.EscapeString(placeholder), key, key, limitAttribute, html.EscapeString(defaultValue))
+			htmlList = append(htmlList, el)
+			if len(limits) > 0 {
+				hint := strings.Join(limits, ", ")
+				htmlList = append(htmlList, fmt.Sprintf(`<small>%s</small>`, strings.ToUpper(hint[:1])+hint[1:]))
+			}
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required})
+			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
+			if maxlength > 0 {
+				validation = append(validation, If(Qual("unicode/utf8", "RuneCountInString").Call(Id("answer").Dot(title)).Op(">").Lit(maxlength)).Block(
+					invalid(key, Lit(fmt.Sprintf("at most %d characters are allowed", maxlength))),
+				))
+			}
+			// strings.Fields splits on any run of whitespace, so doubled spaces and leading or trailing whitespace don't
+			// count as extra words. an optional answer left empty isn't held to minwords
+			if minwords > 0 {
+				words := Len(Qual("strings", "Fields").Call(Id("answer").Dot(title)))
+				tooShort := words.Clone().Op("<").Lit(minwords)
+				if !input.required {
+					tooShort = Id("answer").Dot(title).Op("!=").Lit("").Op("&&").Add(tooShort)
+				}
+				validation = append(validation, If(tooShort).Block(
+					invalid(key, Lit(fmt.Sprintf("at least %d words are required", minwords))),
+				))
+			}
+			if maxwords > 0 {
+				validation = append(validation, If(Len(Qual("strings", "Fields").Call(Id("answer").Dot(title))).Op(">").Lit(maxwords)).Block(
+					invalid(key, Lit(fmt.Sprintf("at most %d words are allowed", maxwords))),
+				))
 			}
-		switch input.element {
-		case "textarea":
+		case "suggest":
 			key, title := formatKeyAndTitle(input)
+			// the list attribute refers to the datalist by id, and ids can't contain whitespace
+			listId := strings.ReplaceAll(key, " ", "-") + "-suggestions"
 			htmlList = append(htmlList, "<div>")
-			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, title))
-			el := fmt.Sprintf(`<textarea %s placeholder="%s" name="%s"></textarea>`, required, input.value, key)
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			el := fmt.Sprintf(`<input type="text" %s list="%s" id="%s" name="%s"/>`, required, listId, key, key)
 			htmlList = append(htmlList, el)
+			htmlList = append(htmlList, fmt.Sprintf(`<datalist id="%s">`, listId))
+			for _, suggestion := range splitOptions(input.value, ',') {
+				htmlList = append(htmlList, fmt.Sprintf(`<option value="%s">`, html.EscapeString(suggestion)))
+			}
+			htmlList = append(htmlList, "</datalist>")
 			htmlList = append(htmlList, "</div>")
-			answer = append(answer, Id(title).String().Tag(jsonTag(key)))
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required})
 			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
-		case "input":
+		case "input", "url", "tel", "email", "password":
 			key, title := formatKeyAndTitle(input)
+			inputType := input.element
+			if input.element == "input" {
+				inputType = "text"
+			}
 			htmlList = append(htmlList, "<div>")
-			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, input.title))
-			el := fmt.Sprintf(`<input type="text" %s placeholder="%s" name="%s"/>`, required, input.value, key)
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			// the content is the placeholder, unless it's a pattern restricting the accepted format, e.g.
+			// `tel[Phone number] = pattern=0[0-9]{9}`. the pattern can be set as a constraint too, alongside the
+			// length limits: `input[Username]{minlength=3, maxlength=20, pattern=[a-z0-9-]+}`
+			placeholder, defaultValue := splitDefault(input)
+			var pattern string
+			// a `{placeholder=...}` constraint takes the placeholder's place, not the pattern's:
+			// `input[Nickname]{placeholder=e.g. Ada} = pattern=[a-z]+`
+			content := input
+			content.constraints = make(map[string]string)
+			for name, value := range input.constraints {
+				if name != "placeholder" {
+					content.constraints[name] = value
+				}
+			}
+			if rhs, _ := splitDefault(content); strings.HasPrefix(rhs, "pattern=") {
+				pattern = strings.TrimPrefix(rhs, "pattern=")
+				if placeholder == rhs {
+					placeholder = ""
+				}
+			}
+			if value, ok := input.constraints["pattern"]; ok {
+				pattern = value
+			}
+			var minlength, maxlength int
+			for name, limit := range map[string]*int{"minlength": &minlength, "maxlength": &maxlength} {
+				value, ok := input.constraints[name]
+				if !ok {
+					continue
+				}
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return 0, nil, fmt.Errorf("%s: %s[%s]: invalid %s %q, expected a positive number", input.position(), input.element, input.title, name, value)
+				}
+				*limit = n
+			}
+			if maxlength > 0 && minlength > maxlength {
+				return 0, nil, fmt.Errorf("%s: %s[%s]: minlength %d is more than maxlength %d", input.position(), input.element, input.title, minlength, maxlength)
+			}
+			if input.element == "email" && placeholder == "" {
+				placeholder = "email@provider.tld"
+			}
+			// a password is never part of the html, nor filled in again, and browsers shouldn't offer saved ones
+			if input.element == "password" && defaultValue != "" {
+				return 0, nil, fmt.Errorf("%s: password[%s] can't have a default value", input.position(), input.title)
+			}
+			var attribute string
+			if placeholder != "" {
+				attribute = fmt.Sprintf(`placeholder="%s"`, html.EscapeString(placeholder))
+			}
+			if pattern != "" {
+				attribute = strings.TrimSpace(fmt.Sprintf(`%s pattern="%s"`, attribute, html.EscapeString(pattern)))
+			}
+			if minlength > 0 {
+				attribute = strings.TrimSpace(fmt.Sprintf(`%s minlength="%d"`, attribute, minlength))
+			}
+			if maxlength > 0 {
+				attribute = strings.TrimSpace(fmt.Sprintf(`%s maxlength="%d"`, attribute, maxlength))
+			}
+			if defaultValue != "" {
+				attribute = strings.TrimSpace(fmt.Sprintf(`%s value="%s"`, attribute, html.EscapeString(defaultValue)))
+			}
+			if input.element == "password" {
+				attribute = strings.TrimSpace(attribute + ` autocomplete="new-password"`)
+			}
+			el := fmt.Sprintf(`<input type="%s" %s %s id="%s" name="%s"/>`, inputType, required, attribute, key, key)
 			htmlList = append(htmlList, el)
 			htmlList = append(htmlList, "</div>")
-			answer = append(answer, Id(title).String().Tag(jsonTag(key)))
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required, sensitive: input.element == "password"})
 			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
+			// browsers accept any scheme for type="url" (e.g. javascript:), only web links are let through
+			if input.element == "url" {
+				validation = append(validation, If(Id("answer").Dot(title).Op("!=").Lit("")).Block(
+					If(
+						List(Id("u"), Err()).Op(":=").Qual("net/url", "ParseRequestURI").Call(Id("answer").Dot(title)),
+						Err().Op("!=").Nil().Op("||").Parens(Id("u").Dot("Scheme").Op("!=").Lit("http").Op("&&").Id("u").Dot("Scheme").Op("!=").Lit("https")),
+					).Block(
+						invalid(key, Lit("must be an http or https url")),
+					),
+				))
+			}
+			// what browsers accept as an email address, unless the field has a pattern of its own
+			if input.element == "email" && pattern == "" {
+				validation = append(validation, If(Id("answer").Dot(title).Op("!=").Lit("").Op("&&").Op("!").Id("emailPattern").Dot("MatchString").Call(Id("answer").Dot(title))).Block(
+					invalid(key, Lit("must be an email address")),
+				))
+				if !emailCheck {
+					patterns = append(patterns, Var().Id("emailPattern").Op("=").Qual("regexp", "MustCompile").Call(Lit(`^[^@\s]+@[^@\s]+$`)))
+					emailCheck = true
+				}
+			}
+			if pattern != "" {
+				// the html pattern attribute has to match the whole value, so the server-side check does too
+				anchored := fmt.Sprintf("^(?:%s)$", pattern)
+				if _, err := regexp.Compile(anchored); err != nil {
+					return 0, nil, fmt.Errorf("%s: %s[%s]: invalid pattern: %v", input.position(), input.element, input.title, err)
+				}
+				// named after the field, which is unique, with a prefix that keeps it apart from the fixed emailPattern
+				// and hexColorPattern
+				patternName := "pattern" + title
+				patterns = append(patterns, Var().Id(patternName).Op("=").Qual("regexp", "MustCompile").Call(Lit(anchored)))
+				validation = append(validation, If(Id("answer").Dot(title).Op("!=").Lit("").Op("&&").Op("!").Id(patternName).Dot("MatchString").Call(Id("answer").Dot(title))).Block(
+					invalid(key, Lit("does not match the expected format")),
+				))
+			}
+			// like the browser, an empty answer isn't held to minlength: that's what `!` is for
+			if minlength > 0 {
+				validation = append(validation, If(Id("answer").Dot(title).Op("!=").Lit("").Op("&&").Qual("unicode/utf8", "RuneCountInString").Call(Id("answer").Dot(title)).Op("<").Lit(minlength)).Block(
+					invalid(key, Lit(fmt.Sprintf("at least %d characters are required", minlength))),
+				))
+			}
+			if maxlength > 0 {
+				validation = append(validation, If(Qual("unicode/utf8", "RuneCountInString").Call(Id("answer").Dot(title)).Op(">").Lit(maxlength)).Block(
+					invalid(key, Lit(fmt.Sprintf("at most %d characters are allowed", maxlength))),
+				))
+			}
 		case "hidden":
 			key, title := formatKeyAndTitle(input)
+			// labelled here, as auto: values skip the rest
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required, element: "hidden", label: unescape(input.title)})
+			// `auto:` values are filled in by the server when receiving the response. they're never part of the html
+			// form, as anything posted by the client could have been tampered with
+			switch input.value {
+			case "auto:uuid":
+				resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("newUUID").Call())
+				autoUUID = true
+				continue
+			case "auto:timestamp":
+				resParse = append(resParse, Id("answer").Dot(title).Op("=").Qual("time", "Now").Call().Dot("UTC").Call().Dot("Format").Call(Qual("time", "RFC3339")))
+				continue
+			}
 			htmlList = append(htmlList, "<div>")
-			el := fmt.Sprintf(`<input type="hidden" %s value="%s" name="%s"/>`, required, input.value, key)
+			el := fmt.Sprintf(`<input type="hidden" %s value="%s" name="%s"/>`, required, html.EscapeString(unescape(input.value)), key)
 			htmlList = append(htmlList, el)
 			htmlList = append(htmlList, "</div>")
-			answer = append(answer, Id(title).String().Tag(jsonTag(key)))
 			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
-		case "form-paragraph":
-			htmlList = append(htmlList, fmt.Sprintf(`<p>%s</p>`, input.value))
-		case "email":
+		case "display":
+			// shown to the respondent, but never read from the request: the answer always holds the value from the
+			// format file, so it can't be tampered with
 			key, title := formatKeyAndTitle(input)
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: true})
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			htmlList = append(htmlList, fmt.Sprintf(`<output id="%s">%s</output>`, key, html.EscapeString(unescape(input.value))))
+			htmlList = append(htmlList, "</div>")
+			resParse = append(resParse, Id("answer").Dot(title).Op("=").Lit(unescape(input.value)))
+		case "section":
+			htmlList = append(htmlList, "<fieldset>")
+			htmlList = append(htmlList, fmt.Sprintf(`<legend>%s</legend>`, html.EscapeString(input.title)))
+			openSections++
+		case "end-section":
+			if openSections == 0 {
+				return 0, nil, fmt.Errorf("end-section without a matching section[...]")
+			}
+			htmlList = append(htmlList, "</fieldset>")
+			openSections--
+		case "form-section":
+			// form-sections don't nest: each one closes the one before it, and an empty one just closes it
+			if openSections > 0 {
+				return 0, nil, fmt.Errorf("form-section can't be used inside of a section[...]")
+			}
+			if formSectionOpen {
+				htmlList = append(htmlList, "</fieldset>")
+				formSectionOpen = false
+			}
+			if input.value != "" {
+				htmlList = append(htmlList, "<fieldset>")
+				htmlList = append(htmlList, fmt.Sprintf(`<legend>%s</legend>`, html.EscapeString(input.value)))
+				formSectionOpen = true
+			}
+		case "form-paragraph":
+			htmlList = append(htmlList, fmt.Sprintf(`<p>%s</p>`, escapeParagraph(input.value)))
+		case "number", "range":
+			// `range[Mood] = min=1, max=5, showvalue` shows the slider's current value next to it
+			content, showValue := input.value, false
+			if input.element == "range" {
+				content, showValue = splitFlag(content, "showvalue")
+			}
+			optionsMap, options := parseOptions(content)
+			if err := checkNumberOptions(optionsMap); err != nil {
+				return 0, nil, fmt.Errorf("%s: %s[%s]: %w", input.position(), input.element, input.title, err)
+			}
+			// a fractional (or "any") step means the input accepts decimals, which wouldn't fit in an int
+			step := optionsMap["step"]
+			fractional := strings.Contains(step, ".") || step == "any"
 			htmlList = append(htmlList, "<div>")
-			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, input.title))
-			el := fmt.Sprintf(`<input type="email" %s placeholder="email@provider.tld" pattern="%s", name="%s"/>`, required, input.value, key)
+			key, title := formatKeyAndTitle(input)
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			// the right-hand side is taken up by the options, so a placeholder can only be set as a constraint
+			if placeholder, ok := input.constraints["placeholder"]; ok {
+				options += fmt.Sprintf(`placeholder="%s" `, html.EscapeString(placeholder))
+			}
+			// the scripts find the output by its place next to the slider, rather than by the key, which could contain
+			// anything (quotes included)
+			var oninput string
+			if showValue {
+				oninput = ` oninput="this.nextElementSibling.value = this.value"`
+			}
+			el := fmt.Sprintf(`<input type="%s" %s %s id="%s" name="%s"%s/>`, input.element, required, options, key, key, oninput)
 			htmlList = append(htmlList, el)
+			if showValue {
+				// the output starts out with whatever value the browser put the slider at
+				htmlList = append(htmlList, fmt.Sprintf(`<output id="%s-value" for="%s"></output>`, key, key))
+				htmlList = append(htmlList, `<script>(output => output.value = output.previousElementSibling.value)(document.currentScript.previousElementSibling)</script>`)
+			}
+			htmlList = append(htmlList, "</div>")
+			// an empty (optional) field is left at zero, anything else has to parse as a number
+			conversion := Id("n").Op(",").Err().Op(":=").Qual("strconv", "Atoi").Call(Id("v"))
+			if fractional {
+				fields = append(fields, answerField{key: key, title: title, kind: "float64", required: input.required})
+				conversion = Id("n").Op(",").Err().Op(":=").Qual("strconv", "ParseFloat").Call(Id("v"), Lit(64))
+			} else {
+				fields = append(fields, answerField{key: key, title: title, kind: "int", required: input.required})
+			}
+			// an answer that isn't a number can't be stored, so that's up to ParsePost(). whether it's within min and
+			// max, or there at all, is up to Validate(), which is told by ParsePost() whether it was answered, as an
+			// unanswered number can't be told apart from a zero
+			var bounds []Code
+			for _, bound := range []struct{ name, op, text string }{{"min", "<", "at least"}, {"max", ">", "at most"}} {
+				value, ok := optionsMap[bound.name]
+				if !ok {
+					continue
+				}
+				limit, _ := strconv.ParseFloat(value, 64)
+				limitCode := Lit(limit)
+				if !fractional {
+					// an int can only be compared with a whole number. rounded inwards, so that it's still within bounds
+					if bound.name == "min" {
+						limit = math.Ceil(limit)
+					} else {
+						limit = math.Floor(limit)
+					}
+					limitCode = Lit(int(limit))
+				}
+				bounds = append(bounds, If(Id("answer").Dot(title).Op(bound.op).Add(limitCode)).Block(
+					invalid(key, Lit(fmt.Sprintf("must be %s %s", bound.text, value))),
+				))
+			}
+			resParse = append(resParse, If(Id("v").Op(":=").Id("req").Dot("PostFormValue").Call(Lit(key)), Id("v").Op("!=").Lit("")).Block(
+				conversion,
+				If(Err().Op("!=").Nil()).Block(
+					Return(fieldError(key, Err().Dot("Error").Call())),
+				),
+				Id("answer").Dot(title).Op("=").Id("n"),
+				Id("answer").Dot("answered").Index(Lit(key)).Op("=").True(),
+			))
+			validation = append(validation, checkNumber(key, title, input.required, bounds)...)
+			tracked = true
+		case "money":
+			// `money[Donation amount] = min=1, max=500, currency=EUR`: answered in cents, so that amounts are exact
+			optionsMap, _ := parseOptions(input.value)
+			key, title := formatKeyAndTitle(input)
+			label := html.EscapeString(input.title)
+			if currency := optionsMap["currency"]; currency != "" {
+				symbol, ok := currencySymbols[currency]
+				if !ok {
+					symbol = currency
+				}
+				label = fmt.Sprintf("%s (%s)", label, html.EscapeString(symbol))
+			}
+			var attributes string
+			for _, name := range []string{"min", "max"} {
+				if value, ok := optionsMap[name]; ok {
+					attributes += fmt.Sprintf(` %s="%s"`, name, html.EscapeString(value))
+				}
+			}
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, label))
+			htmlList = append(htmlList, fmt.Sprintf(`<input type="number" %s step="0.01"%s id="%s" name="%s"/>`, required, attributes, key, key))
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "int64", required: input.required})
+			var bounds []Code
+			for _, bound := range []struct{ name, op, text string }{{"min", "<", "at least"}, {"max", ">", "at most"}} {
+				value, ok := optionsMap[bound.name]
+				if !ok {
+					continue
+				}
+				amount, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return 0, nil, fmt.Errorf("%s: money[%s]: %s=%s is not an amount", input.position(), input.title, bound.name, value)
+				}
+				bounds = append(bounds, If(Id("answer").Dot(title).Op(bound.op).Lit(int(math.Round(amount*100)))).Block(
+					invalid(key, Lit(fmt.Sprintf("must be %s %s", bound.text, value))),
+				))
+			}
+			// like numbers, an amount that can't be read is up to ParsePost(), the rest is up to Validate()
+			resParse = append(resParse, If(Id("v").Op(":=").Id("req").Dot("PostFormValue").Call(Lit(key)), Id("v").Op("!=").Lit("")).Block(
+				List(Id("cents"), Err()).Op(":=").Id("parseCents").Call(Id("v")),
+				If(Err().Op("!=").Nil()).Block(
+					Return(fieldError(key, Err().Dot("Error").Call())),
+				),
+				Id("answer").Dot(title).Op("=").Id("cents"),
+				Id("answer").Dot("answered").Index(Lit(key)).Op("=").True(),
+			))
+			validation = append(validation, checkNumber(key, title, input.required, bounds)...)
+			money = true
+			tracked = true
+		case "color":
+			// `color[Favorite color] = value=#ff0000` sets the initial color, otherwise it's black
+			optionsMap, _ := parseOptions(input.value)
+			key, title := formatKeyAndTitle(input)
+			var value string
+			if optionsMap["value"] != "" {
+				value = fmt.Sprintf(` value="%s"`, html.EscapeString(optionsMap["value"]))
+			}
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			htmlList = append(htmlList, fmt.Sprintf(`<input type="color" %s%s id="%s" name="%s"/>`, required, value, key, key))
 			htmlList = append(htmlList, "</div>")
-			answer = append(answer, Id(title).String().Tag(jsonTag(key)))
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required})
 			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
-		case "number":
-			optionsList := strings.Split(input.value, ",")
-			var options string
+			validation = append(validation, If(Id("answer").Dot(title).Op("!=").Lit("").Op("&&").Op("!").Id("hexColorPattern").Dot("MatchString").Call(Id("answer").Dot(title))).Block(
+				invalid(key, Lit("expected a color like #rrggbb")),
+			))
+			if !hexColor {
+				patterns = append(patterns, Var().Id("hexColorPattern").Op("=").Qual("regexp", "MustCompile").Call(Lit("^#[0-9a-fA-F]{6}$")))
+				hexColor = true
+			}
+		case "yesno":
+			key, title := formatKeyAndTitle(input)
+			// the labels can be changed, e.g. `yesno[Brauchst du einen Parkplatz?] = Ja, Nein`, the posted values can't
+			labels := []string{"Yes", "No"}
+			if custom := splitOptions(input.value, ','); len(custom) == 2 {
+				labels = custom
+			}
 			htmlList = append(htmlList, "<div>")
-			for _, optionPair := range optionsList {
-				optionPair = strings.TrimSpace(optionPair)
-				parts := strings.Split(optionPair, "=")
-				options += fmt.Sprintf(`%s="%s" `,parts[0], parts[1])
+			htmlList = append(htmlList, fmt.Sprintf(`<span>%s</span>`, html.EscapeString(input.title)))
+			for i, radioValue := range []string{"yes", "no"} {
+				radioId := fmt.Sprintf(`%s-option-%s`, key, html.EscapeString(radioValue))
+				htmlList = append(htmlList, "<span>")
+				el := fmt.Sprintf(`<input type="radio" %s id="%s" value="%s" name="%s"/>`, required, radioId, html.EscapeString(radioValue), key)
+				htmlList = append(htmlList, el)
+				htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, radioId, html.EscapeString(labels[i])))
+				htmlList = append(htmlList, "</span>")
+			}
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "bool", required: input.required})
+			resParse = append(resParse, Switch(Id("req").Dot("PostFormValue").Call(Lit(key))).Block(
+				Case(Lit("yes")).Block(
+					Id("answer").Dot(title).Op("=").True(),
+					Id("answer").Dot("answered").Index(Lit(key)).Op("=").True(),
+				),
+				Case(Lit("no")).Block(
+					Id("answer").Dot("answered").Index(Lit(key)).Op("=").True(),
+				),
+			))
+			// a no is false, just like no answer at all, which is why ParsePost() keeps track of it. answers read back
+			// from json were checked when they were posted
+			if input.required {
+				validation = append(validation, If(Op("!").Id("answer").Dot("wasAnswered").Call(Lit(key), True())).Block(
+					invalid(key, Lit("required")),
+				))
 			}
+			tracked = true
+		case "consent":
+			// always required, regardless of the `!` prefix: there's no point in an optional consent
 			key, title := formatKeyAndTitle(input)
-			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, title))
-			el := fmt.Sprintf(`<input type="number" %s %s name="%s"/>`, required, options, key)
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, "<span>")
+			el := fmt.Sprintf(`<input type="checkbox" required aria-required="true" id="%s" name="%s"/>`, key, key)
 			htmlList = append(htmlList, el)
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s"><a href="%s" target="_blank">%s</a></label>`, key, html.EscapeString(input.value), html.EscapeString(input.title)))
+			htmlList = append(htmlList, "</span>")
 			htmlList = append(htmlList, "</div>")
-			answer = append(answer, Id(title).String().Tag(jsonTag(key)))
-			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
-		case "range":
-			optionsList := strings.Split(input.value, ",")
-			var options string
+			fields = append(fields, answerField{key: key, title: title, kind: "bool", required: true})
+			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)).Op("!=").Lit(""))
+			validation = append(validation, If(Op("!").Id("answer").Dot(title)).Block(
+				invalid(key, Lit("consent is required")),
+			))
+		case "multiselect":
+			options := splitOptions(input.value, ',')
+			key, title := formatKeyAndTitle(input)
+			// `{max=N}`, or a `max=N` entry among the options, limits how many of them may be selected
+			max, err := maxConstraint(input)
+			if err != nil {
+				return 0, nil, err
+			}
+			for _, val := range options {
+				if label := strings.TrimSpace(val); strings.HasPrefix(label, "max=") {
+					if n, err := strconv.Atoi(strings.TrimPrefix(label, "max=")); err == nil {
+						max = n
+					}
+				}
+			}
+
 			htmlList = append(htmlList, "<div>")
-			for _, optionPair := range optionsList {
-				optionPair = strings.TrimSpace(optionPair)
-				parts := strings.Split(optionPair, "=")
-				options += fmt.Sprintf(`%s="%s" `,parts[0], parts[1])
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s%s</label>`, key, html.EscapeString(input.title), maxHint(max)))
+			htmlList = append(htmlList, fmt.Sprintf(`<select multiple %s id="%s" name="%s">`, required, key, key))
+			for _, val := range options {
+				label := strings.TrimSpace(val)
+				if label == "" {
+					continue
+				}
+				if strings.HasPrefix(label, "max=") {
+					if _, err := strconv.Atoi(strings.TrimPrefix(label, "max=")); err == nil {
+						continue
+					}
+				}
+				htmlList = append(htmlList, fmt.Sprintf(`<option value="%s">%s</option>`, html.EscapeString(strings.ToLower(label)), html.EscapeString(label)))
 			}
+			htmlList = append(htmlList, "</select>")
+			htmlList = append(htmlList, "</div>")
+			var values []string
+			for _, val := range options {
+				if label := strings.TrimSpace(val); !strings.HasPrefix(label, "max=") {
+					values = append(values, label)
+				}
+			}
+			fields = append(fields, answerField{key: key, title: title, kind: "[]string", required: input.required, options: optionValues(values, false)})
+			resParse = append(resParse, Id("answer").Dot(title).Op("=").Append(Index().String().Values(), Id("req").Dot("PostForm").Index(Lit(key)).Op("...")))
+			if input.required {
+				validation = append(validation, If(Len(Id("answer").Dot(title)).Op("==").Lit(0)).Block(
+					invalid(key, Lit("at least one option must be selected")),
+				))
+			}
+			if max > 0 {
+				validation = append(validation, If(Len(Id("answer").Dot(title)).Op(">").Lit(max)).Block(
+					invalid(key, Lit(fmt.Sprintf("at most %d options can be selected", max))),
+				))
+			}
+		case "file":
+			optionsMap, _ := parseOptions(input.value)
 			key, title := formatKeyAndTitle(input)
-			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, title))
-			el := fmt.Sprintf(`<input type="range" %s %s name="%s"/>`, required, options, key)
+			// restrict the file types that can be picked, e.g. `accept=.pdf`
+			var accept string
+			if optionsMap["accept"] != "" {
+				accept = fmt.Sprintf(`accept="%s"`, html.EscapeString(optionsMap["accept"]))
+			}
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			el := fmt.Sprintf(`<input type="file" %s %s id="%s" name="%s"/>`, required, accept, key, key)
 			htmlList = append(htmlList, el)
 			htmlList = append(htmlList, "</div>")
-			answer = append(answer, Id(title).String().Tag(jsonTag(key)))
-			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
+			// the answer keeps the uploaded file's name, the contents are written to disk by SaveUploads() rather than
+			// being persisted with the rest of the answer
+			dataTitle := title + "Data"
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required})
+			fields = append(fields, answerField{key: "-", title: dataTitle, kind: "[]byte", required: input.required})
+			if optionsMap["maxsize"] != "" {
+				maxsize, err := parseByteSize(optionsMap["maxsize"])
+				if err != nil {
+					return 0, nil, fmt.Errorf("%s: file[%s]: invalid maxsize %q: %w", input.position(), input.title, optionsMap["maxsize"], err)
+				}
+				validation = append(validation, If(Len(Id("answer").Dot(dataTitle)).Op(">").Lit(int(maxsize))).Block(
+					invalid(key, Lit(fmt.Sprintf("file is larger than %s", optionsMap["maxsize"]))),
+				))
+			}
+			resParse = append(resParse, If(
+				List(Id("file"), Id("header"), Err()).Op(":=").Id("req").Dot("FormFile").Call(Lit(key)), Err().Op("==").Nil(),
+			).Block(
+				Defer().Id("file").Dot("Close").Call(),
+				List(Id("data"), Err()).Op(":=").Qual("io", "ReadAll").Call(Id("file")),
+				If(Err().Op("!=").Nil()).Block(
+					Return(fieldError(key, Err().Dot("Error").Call())),
+				),
+				Id("answer").Dot(title).Op("=").Id("header").Dot("Filename"),
+				Id("answer").Dot(dataTitle).Op("=").Id("data"),
+			).Else().If(Op("!").Qual("errors", "Is").Call(Err(), Qual("net/http", "ErrMissingFile"))).Block(
+				Return(fieldError(key, Err().Dot("Error").Call())),
+			))
+			// prefixed with the key, so that two fields uploading files with the same name don't clobber each other
+			uploads = append(uploads, If(Id("answer").Dot(title).Op("!=").Lit("")).Block(
+				If(Err().Op(":=").Qual("os", "MkdirAll").Call(Id("dir"), Op("0777")), Err().Op("!=").Nil()).Block(
+					Return(Err()),
+				),
+				Id("name").Op(":=").Lit(key+"-").Op("+").Qual("path/filepath", "Base").Call(Id("answer").Dot(title)),
+				If(Err().Op(":=").Qual("os", "WriteFile").Call(Qual("path/filepath", "Join").Call(Id("dir"), Id("name")), Id("answer").Dot(dataTitle), Op("0666")), Err().Op("!=").Nil()).Block(
+					Return(Err()),
+				),
+			))
+		case "date":
+			key, title := formatKeyAndTitle(input)
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			// the content is the earliest date that can be picked, e.g. `date[Event date] = 2024-01-01`
+			var min string
+			if input.value != "" {
+				min = fmt.Sprintf(`min="%s"`, html.EscapeString(input.value))
+			}
+			el := fmt.Sprintf(`<input type="date" %s %s id="%s" name="%s"/>`, required, min, key, key)
+			htmlList = append(htmlList, el)
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "time.Time", required: input.required})
+			// browsers post dates as yyyy-mm-dd regardless of how the date picker displays them
+			resParse = append(resParse, parseTimeCode(key, title, "2006-01-02"))
+		case "time", "datetime", "datetime-local":
+			_, options := parseOptions(input.value)
+			key, title := formatKeyAndTitle(input)
+			inputType, layout := "time", "15:04"
+			if input.element != "time" {
+				// posted without seconds, e.g. 2024-01-01T13:37
+				inputType, layout = "datetime-local", "2006-01-02T15:04"
+			}
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			el := fmt.Sprintf(`<input type="%s" %s %s id="%s" name="%s"/>`, inputType, required, options, key, key)
+			htmlList = append(htmlList, el)
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "time.Time", required: input.required})
+			resParse = append(resParse, parseTimeCode(key, title, layout))
 		case "radio":
-			options := strings.Split(input.value, ",")
+			options := splitOptions(input.value, ',')
 			key, title := formatKeyAndTitle(input)
 
+			options, other := splitOtherOption(options)
+			// with an "other" option, picking it makes its text input required
+			var onchange string
+			if other {
+				onchange = fmt.Sprintf(` onchange="%s"`, otherRequiredScript(key))
+			}
+
 			htmlList = append(htmlList, "<div>")
-			htmlList = append(htmlList, fmt.Sprintf(`<span>%s</span>`, input.title))
+			htmlList = append(htmlList, fmt.Sprintf(`<span>%s</span>`, html.EscapeString(input.title)))
 			for i, val := range options {
 				options[i] = strings.TrimSpace(val)
 				radioValue := strings.ToLower(options[i])
-				radioId := fmt.Sprintf(`%s-option-%s`, key, radioValue)
+				radioId := fmt.Sprintf(`%s-option-%s`, key, html.EscapeString(radioValue))
+				htmlList = append(htmlList, "<span>")
+				el := fmt.Sprintf(`<input type="radio" %s id="%s" value="%s" name="%s"%s/>`, required, radioId, html.EscapeString(radioValue), key, onchange)
+				htmlList = append(htmlList, el)
+				htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, radioId, html.EscapeString(options[i])))
+				htmlList = append(htmlList, "</span>")
+
+			}
+			if other {
+				radioId := fmt.Sprintf(`%s-option-other`, key)
+				htmlList = append(htmlList, "<span>")
+				htmlList = append(htmlList, fmt.Sprintf(`<input type="radio" %s id="%s" value="other" name="%s"%s/>`, required, radioId, key, onchange))
+				htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">Other:</label>`, radioId))
+				htmlList = append(htmlList, fmt.Sprintf(`<input type="text" id="%s-other" name="%s-other" aria-label="%s (other)"/>`, key, key, html.EscapeString(input.title)))
+				htmlList = append(htmlList, "</span>")
+			}
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required, enum: optionValues(options, other)})
+			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
+			if other {
+				fields, resParse = appendOtherField(fields, resParse, key, title)
+			}
+		case "checkbox", "checkboxes":
+			options := splitOptions(input.value, ',')
+			key, title := formatKeyAndTitle(input)
+
+			// a single option is a plain yes/no question, e.g. `checkbox[Subscribe] = I want to receive emails`, and
+			// maps to a bool instead of a list of checked values. `checkboxes` is always a group
+			if input.element == "checkbox" && len(options) == 1 {
+				htmlList = append(htmlList, "<div>")
+				htmlList = append(htmlList, fmt.Sprintf(`<span>%s</span>`, html.EscapeString(input.title)))
+				htmlList = append(htmlList, "<span>")
+				el := fmt.Sprintf(`<input type="checkbox" %s id="%s" name="%s"/>`, required, key, key)
+				htmlList = append(htmlList, el)
+				htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(options[0])))
+				htmlList = append(htmlList, "</span>")
+				htmlList = append(htmlList, "</div>")
+				fields = append(fields, answerField{key: key, title: title, kind: "bool", required: input.required})
+				// a checked box without a value attribute posts "on", an unchecked box isn't posted at all
+				resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)).Op("!=").Lit(""))
+				break
+			}
+
+			// checking the exclusive option unchecks all the others, and checking any other unchecks the exclusive one
+			exclusiveValue := strings.ToLower(input.exclusive)
+			exclusiveId := fmt.Sprintf(`%s-option-%s`, key, exclusiveValue)
+			max, err := maxConstraint(input)
+			if err != nil {
+				return 0, nil, err
+			}
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<span>%s%s</span>`, html.EscapeString(input.title), maxHint(max)))
+			for i, val := range options {
+				options[i] = strings.TrimSpace(val)
+				checkboxValue := strings.ToLower(options[i])
+				checkboxId := fmt.Sprintf(`%s-option-%s`, key, html.EscapeString(checkboxValue))
+				var onchange string
+				if input.exclusive != "" && checkboxValue == exclusiveValue {
+					onchange = fmt.Sprintf(` onchange="if (this.checked) for (const box of document.getElementsByName(%s)) box.checked = box === this"`, jsString(key))
+				} else if input.exclusive != "" {
+					onchange = fmt.Sprintf(` onchange="if (this.checked) document.getElementById(%s).checked = false"`, jsString(exclusiveId))
+				}
 				htmlList = append(htmlList, "<span>")
-				el := fmt.Sprintf(`<input type="radio" %s id="%s" value="%s" name="%s"/>`, required, radioId, radioValue, key)
+				// note: no `required` here, on a checkbox that would mean *every* box in the group has to be checked
+				el := fmt.Sprintf(`<input type="checkbox" id="%s" value="%s" name="%s"%s/>`, checkboxId, html.EscapeString(checkboxValue), key, onchange)
 				htmlList = append(htmlList, el)
-				htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, radioId, options[i]))
+				htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, checkboxId, html.EscapeString(options[i])))
 				htmlList = append(htmlList, "</span>")
+			}
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "[]string", required: input.required, options: optionValues(options, false)})
+			// every checked box is posted under the same key, so PostFormValue (which only returns the first value)
+			// won't do. copying into an empty slice means no boxes checked => [] rather than null
+			resParse = append(resParse, Id("answer").Dot(title).Op("=").Append(Index().String().Values(), Id("req").Dot("PostForm").Index(Lit(key)).Op("...")))
+			// html can't express "at least one of these boxes" for a group, so required is checked in Validate()
+			if input.required {
+				validation = append(validation, If(Len(Id("answer").Dot(title)).Op("==").Lit(0)).Block(
+					invalid(key, Lit("at least one option must be checked")),
+				))
+			}
+			if input.exclusive != "" {
+				validation = append(validation, If(Len(Id("answer").Dot(title)).Op(">").Lit(1)).Block(
+					For(List(Id("_"), Id("v")).Op(":=").Range().Id("answer").Dot(title)).Block(
+						If(Id("v").Op("==").Lit(exclusiveValue)).Block(
+							invalid(key, Lit(fmt.Sprintf("%q can't be checked together with other options", input.exclusive))),
+							Break(),
+						),
+					),
+				))
+			}
+			if max > 0 {
+				validation = append(validation, If(Len(Id("answer").Dot(title)).Op(">").Lit(max)).Block(
+					invalid(key, Lit(fmt.Sprintf("at most %d options can be checked", max))),
+				))
+			}
+		case "likert":
+			// `likert[How was the event?] = Venue; Food; Talks | Bad, Okay, Great`: one row of radios per statement
+			// before the pipe, with the scale after it as columns
+			parts := splitRaw(input.value, '|')
+			if len(parts) != 2 {
+				return 0, nil, fmt.Errorf("%s: likert[%s]: expected statements and scale separated by a pipe, e.g. `Venue; Food | Bad, Okay, Great`", input.position(), input.title)
+			}
+			rows := splitOptions(parts[0], ';')
+			columns := splitOptions(parts[1], ',')
+			key, _ := formatKeyAndTitle(input)
+
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<span>%s</span>`, html.EscapeString(input.title)))
+			htmlList = append(htmlList, "<table>")
+			header := "<tr><th></th>"
+			for _, column := range columns {
+				header += fmt.Sprintf("<th>%s</th>", html.EscapeString(column))
+			}
+			htmlList = append(htmlList, header+"</tr>")
+			for _, row := range rows {
+				row = strings.TrimSpace(row)
+				// every statement is its own radio group, and gets its own field in the answer
+				rowKey, rowTitle := formatKeyAndTitle(genValue{key: slugify(key) + "-" + slugify(row)})
+				htmlRow := fmt.Sprintf("<tr><td>%s</td>", html.EscapeString(row))
+				for _, column := range columns {
+					el := fmt.Sprintf(`<input type="radio" %s value="%s" name="%s" aria-label="%s: %s"/>`, required, html.EscapeString(strings.ToLower(column)), rowKey, html.EscapeString(row), html.EscapeString(column))
+					htmlRow += fmt.Sprintf("<td>%s</td>", el)
+				}
+				htmlList = append(htmlList, htmlRow+"</tr>")
+				fields = append(fields, answerField{key: rowKey, title: rowTitle, kind: "string", required: input.required, label: input.title + ": " + row, options: optionValues(columns, false)})
+				resParse = append(resParse, Id("answer").Dot(rowTitle).Op("=").Id("req").Dot("PostFormValue").Call(Lit(rowKey)))
+			}
+			htmlList = append(htmlList, "</table>")
+			htmlList = append(htmlList, "</div>")
+		case "matrix":
+			// `matrix[Availability] = Mon, Tue, Wed | Morning, Afternoon, Evening`: a table of checkboxes, with the rows
+			// before the pipe and the columns after it. any number of columns can be checked in every row
+			parts := splitRaw(input.value, '|')
+			if len(parts) != 2 {
+				return 0, nil, fmt.Errorf("%s: matrix[%s]: expected rows and columns separated by a pipe, e.g. `Mon, Tue | Morning, Evening`", input.position(), input.title)
+			}
+			rows := splitOptions(parts[0], ',')
+			columns := splitOptions(parts[1], ',')
+			if len(rows) == 0 || len(columns) == 0 {
+				return 0, nil, fmt.Errorf("%s: matrix[%s]: needs at least one row and one column", input.position(), input.title)
+			}
+			key, title := formatKeyAndTitle(input)
+
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<span>%s</span>`, html.EscapeString(input.title)))
+			htmlList = append(htmlList, "<table>")
+			header := "<tr><th></th>"
+			for _, column := range columns {
+				header += fmt.Sprintf("<th>%s</th>", html.EscapeString(column))
+			}
+			htmlList = append(htmlList, header+"</tr>")
+			var matrixRows []Code
+			for _, row := range rows {
+				rowKey := slugify(key) + "-" + slugify(row)
+				htmlRow := fmt.Sprintf("<tr><td>%s</td>", html.EscapeString(row))
+				for _, column := range columns {
+					el := fmt.Sprintf(`<input type="checkbox" value="%s" name="%s" aria-label="%s: %s"/>`, html.EscapeString(strings.ToLower(column)), rowKey, html.EscapeString(row), html.EscapeString(column))
+					htmlRow += fmt.Sprintf("<td>%s</td>", el)
+				}
+				htmlList = append(htmlList, htmlRow+"</tr>")
+				matrixRows = append(matrixRows, Values(Lit(rowKey), Lit(row)))
+			}
+			htmlList = append(htmlList, "</table>")
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "map[string][]string", required: input.required})
+			// the answer maps every row with checked boxes to its checked columns, rows without any are left out
+			resParse = append(resParse,
+				Id("answer").Dot(title).Op("=").Make(Map(String()).Index().String()),
+				For(List(Id("_"), Id("row")).Op(":=").Range().Index().Index(Lit(2)).String().Custom(Options{Open: "{", Close: "}", Separator: ",", Multi: true}, matrixRows...)).Block(
+					If(Id("checked").Op(":=").Id("req").Dot("PostForm").Index(Id("row").Index(Lit(0))), Len(Id("checked")).Op(">").Lit(0)).Block(
+						Id("answer").Dot(title).Index(Id("row").Index(Lit(1))).Op("=").Append(Index().String().Values(), Id("checked").Op("...")),
+					),
+				),
+			)
+			if input.required {
+				validation = append(validation, If(Len(Id("answer").Dot(title)).Op("==").Lit(0)).Block(
+					invalid(key, Lit("at least one box must be checked")),
+				))
+			}
+		case "country":
+			key, title := formatKeyAndTitle(input)
+			// `only:AT,DE,CH` restricts the dropdown to the listed country codes
+			var only map[string]bool
+			if strings.HasPrefix(input.value, "only:") {
+				only = make(map[string]bool)
+				for _, code := range splitOptions(strings.TrimPrefix(input.value, "only:"), ',') {
+					only[strings.ToUpper(strings.TrimSpace(code))] = true
+				}
+			}
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			htmlList = append(htmlList, fmt.Sprintf(`<select %s id="%s" name="%s">`, required, key, key))
+			htmlList = append(htmlList, `<option value=""></option>`)
+			for _, country := range countries {
+				if only != nil && !only[country[0]] {
+					continue
+				}
+				htmlList = append(htmlList, fmt.Sprintf(`<option value="%s">%s</option>`, country[0], country[1]))
+			}
+			htmlList = append(htmlList, "</select>")
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required})
+			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
+		case "repeat":
+			// `repeat[Attendee] = 1..5` repeats the indented elements below it up to 5 times, the first of which are
+			// required. each repetition is answered as an entry of a []Attendee field
+			var min, max int
+			if _, err := fmt.Sscanf(input.value, "%d..%d", &min, &max); err != nil || max < 1 || min > max {
+				return 0, nil, fmt.Errorf("%s: repeat[%s]: expected the number of repetitions as min..max, e.g. `1..5`", input.position(), input.title)
+			}
+			key, title := formatKeyAndTitle(input)
+			var entryFields []Code
+			var entryParse []Code
+			var entries []answerField
+			for _, child := range input.children {
+				childKey, childTitle := formatKeyAndTitle(child)
+				switch child.element {
+				case "input", "textarea", "email", "url", "tel":
+				default:
+					return 0, nil, fmt.Errorf("%s: repeat[%s]: %s elements can't be repeated", child.position(), input.title, child.element)
+				}
+				if child.help != "" {
+					return 0, nil, fmt.Errorf("%s: help text can't be shown for repeated elements", child.position())
+				}
+				entryFields = append(entryFields, Id(childTitle).String().Tag(fieldTags(childKey, child.required)))
+				entries = append(entries, answerField{key: childKey, title: childTitle, kind: "string", required: child.required})
+				entryParse = append(entryParse, Id(childTitle).Op(":").Id("req").Dot("PostFormValue").Call(
+					Qual("fmt", "Sprintf").Call(Lit(slugify(key)+"-%d-"+slugify(childKey)), Id("i")),
+				))
+			}
+			for i := 1; i <= max; i++ {
+				htmlList = append(htmlList, "<fieldset>")
+				htmlList = append(htmlList, fmt.Sprintf(`<legend>%s %d</legend>`, html.EscapeString(input.title), i))
+				for _, child := range input.children {
+					childKey, _ := formatKeyAndTitle(child)
+					indexedKey := fmt.Sprintf("%s-%d-%s", slugify(key), i, slugify(childKey))
+					// only the entries up to the minimum amount are required
+					var childRequired string
+					if child.required && i <= min {
+						childRequired = `required aria-required="true"`
+					}
+					htmlList = append(htmlList, "<div>")
+					htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, indexedKey, html.EscapeString(child.title)))
+					var el string
+					switch child.element {
+					case "textarea":
+						el = fmt.Sprintf(`<textarea %s placeholder="%s" id="%s" name="%s"></textarea>`, childRequired, html.EscapeString(child.value), indexedKey, indexedKey)
+					case "input":
+						el = fmt.Sprintf(`<input type="text" %s placeholder="%s" id="%s" name="%s"/>`, childRequired, html.EscapeString(child.value), indexedKey, indexedKey)
+					default:
+						el = fmt.Sprintf(`<input type="%s" %s placeholder="%s" id="%s" name="%s"/>`, child.element, childRequired, html.EscapeString(child.value), indexedKey, indexedKey)
+					}
+					htmlList = append(htmlList, el)
+					htmlList = append(htmlList, "</div>")
+				}
+				htmlList = append(htmlList, "</fieldset>")
+			}
+			types = append(types, Type().Id(title).Struct(entryFields...))
+			enumNames[title] = true
+			fields = append(fields, answerField{key: key, title: title + "s", kind: "[]" + title, required: min > 0, entries: entries})
+			// entries that were left entirely empty aren't part of the answer
+			resParse = append(resParse, For(Id("i").Op(":=").Lit(1), Id("i").Op("<=").Lit(max), Id("i").Op("++")).Block(
+				Id("entry").Op(":=").Id(title).Custom(Options{Open: "{", Close: "}", Separator: ",", Multi: true}, entryParse...),
+				If(Id("entry").Op("!=").Parens(Id(title).Values())).Block(
+					Id("answer").Dot(title+"s").Op("=").Append(Id("answer").Dot(title+"s"), Id("entry")),
+				),
+			))
+			if min > 0 {
+				validation = append(validation, If(Len(Id("answer").Dot(title+"s")).Op("<").Lit(min)).Block(
+					invalid(key, Lit(fmt.Sprintf("at least %d required", min))),
+				))
+			}
+		case "rank":
+			// every option gets a dropdown of the ranks 1..N, and the answer is the options in order of their rank
+			key, title := formatKeyAndTitle(input)
+			options := splitOptions(input.value, ',')
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<span>%s</span>`, html.EscapeString(input.title)))
+			var rankOptions []Code
+			for _, option := range options {
+				optionKey := fmt.Sprintf("%s-%s", slugify(key), slugify(option))
+				htmlList = append(htmlList, "<div>")
+				htmlList = append(htmlList, fmt.Sprintf(`<select %s id="%s" name="%s">`, required, optionKey, optionKey))
+				htmlList = append(htmlList, `<option value=""></option>`)
+				for i := 1; i <= len(options); i++ {
+					htmlList = append(htmlList, fmt.Sprintf(`<option value="%d">%d</option>`, i, i))
+				}
+				htmlList = append(htmlList, "</select>")
+				htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, optionKey, html.EscapeString(option)))
+				htmlList = append(htmlList, "</div>")
+				rankOptions = append(rankOptions, Values(Lit(optionKey), Lit(option)))
+			}
+			htmlList = append(htmlList, "</div>")
+			fields = append(fields, answerField{key: key, title: title, kind: "[]string", required: input.required, options: options})
+			resParse = append(resParse, Block(
+				List(Id("ranked"), Err()).Op(":=").Id("parseRanking").Call(
+					Id("req"), Lit(key), Index().Index(Lit(2)).String().Custom(Options{Open: "{", Close: "}", Separator: ",", Multi: true}, rankOptions...),
+				),
+				If(Err().Op("!=").Nil()).Block(Return(Err())),
+				Id("answer").Dot(title).Op("=").Id("ranked"),
+			))
+			ranking = true
+			if input.required {
+				validation = append(validation, If(Len(Id("answer").Dot(title)).Op("==").Lit(0)).Block(
+					invalid(key, Lit("required")),
+				))
+			}
+		case "select":
+			options := splitOptions(input.value, ',')
+			key, title := formatKeyAndTitle(input)
 
+			htmlList = append(htmlList, "<div>")
+			htmlList = append(htmlList, fmt.Sprintf(`<label for="%s">%s</label>`, key, html.EscapeString(input.title)))
+			options, other := splitOtherOption(options)
+			var onchange string
+			if other {
+				onchange = fmt.Sprintf(` onchange="%s"`, otherRequiredScript(key))
+			}
+			htmlList = append(htmlList, fmt.Sprintf(`<select %s id="%s" name="%s"%s>`, required, key, key, onchange))
+			// leading blank option, so that the field starts out unselected. for required selects this is also what
+			// makes the browser force a choice: the empty value doesn't satisfy `required`
+			htmlList = append(htmlList, `<option value=""></option>`)
+			for _, val := range options {
+				label := strings.TrimSpace(val)
+				// skip the empties left behind by e.g. a trailing comma
+				if label == "" {
+					continue
+				}
+				htmlList = append(htmlList, fmt.Sprintf(`<option value="%s">%s</option>`, html.EscapeString(strings.ToLower(label)), html.EscapeString(label)))
+			}
+			if other {
+				htmlList = append(htmlList, `<option value="other">Other</option>`)
+			}
+			htmlList = append(htmlList, "</select>")
+			if other {
+				htmlList = append(htmlList, fmt.Sprintf(`<input type="text" id="%s-other" name="%s-other" aria-label="%s (other)"/>`, key, key, html.EscapeString(input.title)))
 			}
 			htmlList = append(htmlList, "</div>")
-			answer = append(answer, Id(title).String().Tag(jsonTag(key)))
+			fields = append(fields, answerField{key: key, title: title, kind: "string", required: input.required, enum: optionValues(options, other)})
 			resParse = append(resParse, Id("answer").Dot(title).Op("=").Id("req").Dot("PostFormValue").Call(Lit(key)))
+			if other {
+				fields, resParse = appendOtherField(fields, resParse, key, title)
+			}
+		}
+		if input.help != "" {
+			if htmlList, err = appendHelp(htmlList, elementStart, input); err != nil {
+				return 0, nil, err
+			}
+		}
+		// what's known about the fields for the generated Fields. fields other than the element's first, like the text
+		// of an "other" option, go by their key
+		for i := range fields[fieldsStart:] {
+			field := &fields[fieldsStart+i]
+			field.element = input.element
+			if field.label == "" && i == 0 {
+				field.label = unescape(input.title)
+			} else if field.label == "" {
+				field.label = field.key
+			}
+			if field.options == nil {
+				field.options = field.enum
+			}
+		}
+		// `{private}` answers are parsed and checked like any other, but redacted wherever they're stored or shown,
+		// like passwords are
+		if value, ok := input.constraints["private"]; ok && value != "false" {
+			if input.element == "file" {
+				return 0, nil, fmt.Errorf("%s: file[%s] can't be private, uploads are always saved", input.position(), input.title)
+			}
+			for i := range fields[fieldsStart:] {
+				fields[fieldsStart+i].sensitive = true
+			}
+		}
+		// required answers are checked by the server too. numbers are checked along with their bounds (see checkNumber),
+		// as an unanswered number can't be told apart from a zero here, and lists and the like have checks of their own.
+		// the options that can be picked are checked too, when there are any. these come before the element's own checks
+		var checks []Code
+		for _, field := range fields[fieldsStart:] {
+			answer := Id("answer").Dot(field.title)
+			switch {
+			case !input.required || !field.required:
+			case field.kind == "string":
+				checks = append(checks, If(answer.Clone().Op("==").Lit("")).Block(invalid(field.key, Lit("required"))))
+			case field.kind == "time.Time":
+				checks = append(checks, If(answer.Clone().Dot("IsZero").Call()).Block(invalid(field.key, Lit("required"))))
+			}
+			if len(field.enum) > 0 && field.kind == "string" {
+				// an unanswered optional field is fine, and the options may well repeat themselves, which a switch can't
+				seen := map[string]bool{"": true}
+				allowed := []Code{Lit("")}
+				var constants, valid []Code
+				// each option gets a constant, e.g. SizeSmall for the small option of Size, and all of them are listed in
+				// ValidSizes. an option that can't be made into a name of its own is used as is
+				for _, value := range field.enum {
+					if seen[value] {
+						continue
+					}
+					seen[value] = true
+					option := Lit(value)
+					if name := identifier(value); name != "" && !enumNames[field.title+name] {
+						enumNames[field.title+name] = true
+						constants = append(constants, Id(field.title+name).Op("=").Lit(value))
+						option = Id(field.title + name)
+					}
+					allowed = append(allowed, option)
+					valid = append(valid, option)
+				}
+				plural := field.title + "s"
+				switch {
+				case strings.HasSuffix(field.title, "s"):
+					// most likely plural already, like Options
+					plural = field.title
+				case strings.HasSuffix(field.title, "x"):
+					plural = field.title + "es"
+				case len(field.title) > 1 && strings.HasSuffix(field.title, "y") && !strings.ContainsAny(field.title[len(field.title)-2:len(field.title)-1], "aeiouAEIOU"):
+					plural = strings.TrimSuffix(field.title, "y") + "ies"
+				}
+				if len(constants) > 0 {
+					enums = append(enums, Comment(fmt.Sprintf("the options of %s", field.title)), Const().Defs(constants...))
+				}
+				if !enumNames["Valid"+plural] {
+					enumNames["Valid"+plural] = true
+					enums = append(enums, Comment(fmt.Sprintf("Valid%s are the options that %s can be answered with", plural, field.title)),
+						Var().Id("Valid"+plural).Op("=").Index().String().Values(valid...))
+				}
+				checks = append(checks, Switch(answer.Clone()).Block(
+					Case(allowed...),
+					Default().Block(invalid(field.key, Lit("not one of the options"))),
+				))
+			}
+		}
+		validation = append(validation[:validationStart], append(checks, validation[validationStart:]...)...)
+		// the spots where the form server shows what's wrong with an answer, when showing the form again
+		if end := len(htmlList) - 1; end >= elementStart && htmlList[end] == "</div>" {
+			var slots []string
+			for _, field := range fields[fieldsStart:] {
+				// fields that aren't part of the answer, like a file's contents, aren't checked either
+				if field.key == "-" {
+					continue
+				}
+				slots = append(slots, fmt.Sprintf(`<small class="field-error" id="%s-error" data-for="%s"></small>`, slugify(field.key), html.EscapeString(field.key)))
+			}
+			htmlList = append(htmlList[:end], append(slots, "</div>")...)
+		}
+		// `{if=attending-as=business}` only shows the field while the attending-as field is answered with business. a
+		// hidden field's inputs are disabled, so that they aren't posted, nor required, and its checks are skipped.
+		// `{show-if=attending-as==business}` is the same
+		condition, ok := input.constraints["if"]
+		separator := "="
+		if showIf, showOk := input.constraints["show-if"]; showOk {
+			condition, separator, ok = showIf, "==", true
+		}
+		if ok {
+			field, value, err := conditionField(condition, separator, fields, keyLines)
+			if err != nil {
+				return 0, nil, fmt.Errorf("%s: %s[%s]: %w", input.position(), input.element, input.title, err)
+			}
+			if htmlList[elementStart] != "<div>" {
+				return 0, nil, fmt.Errorf("%s: %s elements can't be shown conditionally", input.position(), input.element)
+			}
+			htmlList[elementStart] = fmt.Sprintf(`<div data-show-if="%s">`, html.EscapeString(field.key+"=="+value))
+			conditional := append([]Code{}, validation[validationStart:]...)
+			if len(conditional) > 0 {
+				validation = append(validation[:validationStart], If(Id("answer").Dot(field.title).Op("==").Lit(value)).Block(conditional...))
+			}
+			conditions = true
 		}
 	}
 
+	if openSections > 0 {
+		return 0, nil, fmt.Errorf("%d section[...] without a matching end-section", openSections)
+	}
+	if formSectionOpen {
+		htmlList = append(htmlList, "</fieldset>")
+	}
+	// screen readers announce whatever ends up in here, so it's the place for the server to put validation errors when
+	// re-rendering the form
+	htmlList = append(htmlList, `<div id="form-errors" role="alert" aria-live="assertive"></div>`)
+	if conditions {
+		htmlList = append(htmlList, conditionScript)
+	}
 	htmlList = append(htmlList, `<div><button type="submit">Submit</button></div>`)
 	htmlList = append(htmlList, "</form>")
 
-	// set BasicPassword const
-	f.Const().Id("BasicPassword").Op("=").Lit(setPassword)
+	// set BasicPasswordHash const. only the bcrypt hash of the password ends up in the generated code, so that the
+	// generated package can be committed without leaking the password
+	var passwordHash string
+	if setPassword != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(setPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return 0, nil, fmt.Errorf("err hashing form-password: %w", err)
+		}
+		passwordHash = string(hash)
+	}
+	f.Const().Id("BasicPasswordHash").Op("=").Lit(passwordHash)
 	f.Const().Id("BasicUser").Op("=").Lit(setUser)
 	// generate FormContent struct
 	f.Type().Id("FormContent").Struct(contentBits...)
-	// generate FormAnswer struct
+	// generate FormAnswer struct. with --sort-fields its fields are sorted by key, and so is everything that follows
+	// their order, like the stored json and the csv columns. the parsing code stays in the order of the form
+	if opts.sortFields {
+		sort.SliceStable(fields, func(i, j int) bool {
+			return fields[i].key < fields[j].key
+		})
+	}
+	var answer []Code
+	for _, field := range fields {
+		answer = append(answer, Id(field.title).Add(field.typeCode()).Tag(fieldTags(field.key, field.required)))
+	}
+	if tracked {
+		answer = append(answer, Line(), Comment("the keys of the numbers and yes/no questions that were answered, set by ParsePost()"), Id("answered").Map(String()).Bool())
+	}
 	f.Type().Id("FormAnswer").Struct(answer...)
+	for _, t := range types {
+		f.Add(t)
+	}
+	for _, enum := range enums {
+		f.Add(enum)
+	}
 
+	// the body is read up front, rather than leaving it to PostFormValue, which ignores malformed, truncated or
+	// too large bodies
+	f.Comment("MaxBodySize is the most a posted answer can take up, in bytes. the form server rejects larger ones, handlers of")
+	f.Comment("your own can do the same with http.MaxBytesReader(w, req.Body, MaxBodySize) before calling ParsePost")
+	f.Const().Id("MaxBodySize").Op("=").Lit(maxBody)
+	parseBody := Id("req").Dot("ParseForm").Call()
+	if multipart {
+		// keeps up to 32mb of the uploads in memory, the rest is buffered in temporary files
+		parseBody = Id("req").Dot("ParseMultipartForm").Call(Lit(32).Op("<<").Lit(20))
+	}
+	f.Comment("parseRequest reads the posted form. it can be called more than once")
+	f.Func().Id("parseRequest").Params(Id("req").Op("*").Qual("net/http", "Request")).Error().Block(
+		Return(parseBody),
+	)
+	var parseStart []Code
+	if tracked {
+		parseStart = append(parseStart, Id("answer").Dot("answered").Op("=").Make(Map(String()).Bool()))
+	}
+	resParse = append(append([]Code{If(Err().Op(":=").Id("parseRequest").Call(Id("req")), Err().Op("!=").Nil()).Block(
+		Return(Err()),
+	)}, parseStart...), resParse...)
+	resParse = append(resParse, Return(Nil()))
+	for _, pattern := range patterns {
+		f.Add(pattern)
+	}
 	// generate FormAnswer.ParsePost() 
 	f.Func().Params(
 		Id("answer").Id("*FormAnswer"),
 	).Id("ParsePost").Params(
 		Id("req").Op("*").Qual("net/http", "Request"),
-	).Block(resParse...)
+	).Error().Block(resParse...)
+
+	if autoUUID {
+		f.Comment("newUUID generates a random (version 4) uuid")
+		f.Func().Id("newUUID").Params().String().Block(
+			Id("b").Op(":=").Make(Index().Byte(), Lit(16)),
+			Qual("crypto/rand", "Read").Call(Id("b")),
+			Id("b").Index(Lit(6)).Op("=").Id("b").Index(Lit(6)).Op("&").Op("0x0f").Op("|").Op("0x40"),
+			Id("b").Index(Lit(8)).Op("=").Id("b").Index(Lit(8)).Op("&").Op("0x3f").Op("|").Op("0x80"),
+			Return(Qual("fmt", "Sprintf").Call(Lit("%x-%x-%x-%x-%x"), Id("b").Index(Lit(0), Lit(4)), Id("b").Index(Lit(4), Lit(6)), Id("b").Index(Lit(6), Lit(8)), Id("b").Index(Lit(8), Lit(10)), Id("b").Index(Lit(10), Empty()))),
+		)
+	}
+
+	if ranking {
+		f.Comment("parseRanking orders the options, given as {form key, option}, by the rank each one was given. ranks have to be")
+		f.Comment("unique, and either all options or none of them have to be ranked")
+		f.Func().Id("parseRanking").Params(
+			Id("req").Op("*").Qual("net/http", "Request"), Id("key").String(), Id("options").Index().Index(Lit(2)).String(),
+		).Params(Index().String(), Error()).Block(
+			Id("ranked").Op(":=").Make(Index().String(), Len(Id("options"))),
+			Var().Id("given").Int(),
+			For(List(Id("_"), Id("option")).Op(":=").Range().Id("options")).Block(
+				Id("v").Op(":=").Id("req").Dot("PostFormValue").Call(Id("option").Index(Lit(0))),
+				If(Id("v").Op("==").Lit("")).Block(Continue()),
+				List(Id("rank"), Err()).Op(":=").Qual("strconv", "Atoi").Call(Id("v")),
+				If(Err().Op("!=").Nil().Op("||").Id("rank").Op("<").Lit(1).Op("||").Id("rank").Op(">").Len(Id("options"))).Block(
+					Return(Nil(), Op("&").Id("FieldError").Values(Dict{Id("Key"): Id("key"), Id("Message"): Qual("fmt", "Sprintf").Call(Lit("%q is not a valid rank"), Id("v"))})),
+				),
+				If(Id("ranked").Index(Id("rank").Op("-").Lit(1)).Op("!=").Lit("")).Block(
+					Return(Nil(), Op("&").Id("FieldError").Values(Dict{Id("Key"): Id("key"), Id("Message"): Qual("fmt", "Sprintf").Call(Lit("rank %d was given to more than one option"), Id("rank"))})),
+				),
+				Id("ranked").Index(Id("rank").Op("-").Lit(1)).Op("=").Id("option").Index(Lit(1)),
+				Id("given").Op("++"),
+			),
+			If(Id("given").Op("==").Lit(0)).Block(Return(Nil(), Nil())),
+			If(Id("given").Op("<").Len(Id("options"))).Block(
+				Return(Nil(), Op("&").Id("FieldError").Values(Dict{Id("Key"): Id("key"), Id("Message"): Lit("every option needs a rank")})),
+			),
+			Return(Id("ranked"), Nil()),
+		)
+	}
+
+	if money {
+		f.Comment("parseCents parses an amount of money, like 12.50, 12,50 or 12, into cents")
+		f.Func().Id("parseCents").Params(Id("s").String()).Params(Int64(), Error()).Block(
+			Id("s").Op("=").Qual("strings", "Replace").Call(Qual("strings", "TrimSpace").Call(Id("s")), Lit(","), Lit("."), Lit(1)),
+			List(Id("whole"), Id("fraction"), Id("_")).Op(":=").Qual("strings", "Cut").Call(Id("s"), Lit(".")),
+			If(Len(Id("fraction")).Op(">").Lit(2)).Block(
+				Return(Lit(0), Qual("errors", "New").Call(Lit("at most two decimals are allowed"))),
+			),
+			// 12.5 is 12.50
+			For(Len(Id("fraction")).Op("<").Lit(2)).Block(
+				Id("fraction").Op("+=").Lit("0"),
+			),
+			// .50 is 0.50
+			If(Id("whole").Op("==").Lit("")).Block(
+				Id("whole").Op("=").Lit("0"),
+			),
+			List(Id("units"), Err()).Op(":=").Qual("strconv", "ParseUint").Call(Id("whole"), Lit(10), Lit(56)),
+			If(Err().Op("!=").Nil()).Block(
+				Return(Lit(0), Qual("fmt", "Errorf").Call(Lit("%q is not an amount of money"), Id("s"))),
+			),
+			List(Id("cents"), Err()).Op(":=").Qual("strconv", "ParseUint").Call(Id("fraction"), Lit(10), Lit(8)),
+			If(Err().Op("!=").Nil()).Block(
+				Return(Lit(0), Qual("fmt", "Errorf").Call(Lit("%q is not an amount of money"), Id("s"))),
+			),
+			Return(Int64().Call(Id("units").Op("*").Lit(100).Op("+").Id("cents")), Nil()),
+		)
+	}
+
+	if tracked {
+		f.Comment("wasAnswered tells whether the field under key was answered. answers that weren't parsed by ParsePost(), like")
+		f.Comment("ones read back from json, go by fallback instead, e.g. whether a number isn't zero")
+		f.Func().Params(
+			Id("answer").Id("*FormAnswer"),
+		).Id("wasAnswered").Params(Id("key").String(), Id("fallback").Bool()).Bool().Block(
+			If(Id("answer").Dot("answered").Op("==").Nil()).Block(Return(Id("fallback"))),
+			Return(Id("answer").Dot("answered").Index(Id("key"))),
+		)
+	}
+	// generate FormAnswer.Validate()
+	validation = append([]Code{Var().Id("errs").Index().Id("FieldError")}, validation...)
+	validation = append(validation, Return(Id("errs")))
+	f.Comment("Validate checks the answer for mistakes that the browser should have caught, but which anyone posting to the")
+	f.Comment("form server directly can get past, returning all of them")
+	f.Func().Params(
+		Id("answer").Id("*FormAnswer"),
+	).Id("Validate").Params().Index().Id("FieldError").Block(validation...)
+
+	// generate FormAnswer.SaveUploads()
+	uploads = append(uploads, Return(Nil()))
+	f.Comment("SaveUploads writes the files uploaded with the answer into dir")
+	f.Func().Params(
+		Id("answer").Id("*FormAnswer"),
+	).Id("SaveUploads").Params(Id("dir").String()).Error().Block(uploads...)
+
+	// generate FormAnswerCSVHeader() and FormAnswer.CSVRecord(), in the same (declaration) order
+	var csvHeader, csvRow []Code
+	var csvTime, csvJSON bool
+	for _, field := range fields {
+		// fields that aren't serialized to json aren't exported either
+		if field.key == "-" {
+			continue
+		}
+		csvHeader = append(csvHeader, Lit(field.key))
+		csvRow = append(csvRow, field.csvCode())
+		csvTime = csvTime || field.kind == "time.Time"
+		csvJSON = csvJSON || strings.HasPrefix(field.kind, "map[") || (strings.HasPrefix(field.kind, "[]") && field.kind != "[]string")
+	}
+	multiline := Options{Open: "{", Close: "}", Separator: ",", Multi: true}
+	f.Comment("FormAnswerCSVHeader returns the header row for exporting answers as csv, matching the cells of FormAnswer.CSVRecord")
+	f.Func().Id("FormAnswerCSVHeader").Params().Index().String().Block(
+		Return(Index().String().Custom(multiline, csvHeader...)),
+	)
+	f.Comment("CSVRecord returns the answer as a csv record, with sensitive answers redacted")
+	f.Func().Params(
+		Id("answer").Id("FormAnswer"),
+	).Id("CSVRecord").Params().Index().String().Block(
+		Id("answer").Op("=").Op("*").Id("answer").Dot("Redacted").Call(),
+		Return(Index().String().Custom(multiline, csvRow...)),
+	)
+
+	// generate FieldInfo, Fields and FormAnswer.Map(), for showing answers without knowing the form, in the same
+	// (declaration) order as the csv
+	var infos []Code
+	mapped := Dict{}
+	for _, field := range fields {
+		if field.key == "-" {
+			continue
+		}
+		info := []Code{
+			Id("Key").Op(":").Lit(field.key),
+			Id("Label").Op(":").Lit(field.label),
+			Id("Element").Op(":").Lit(field.element),
+		}
+		if field.required {
+			info = append(info, Id("Required").Op(":").True())
+		}
+		if len(field.options) > 0 {
+			var options []Code
+			for _, option := range field.options {
+				options = append(options, Lit(option))
+			}
+			info = append(info, Id("Options").Op(":").Index().String().Values(options...))
+		}
+		infos = append(infos, Values(info...))
+		mapped[Lit(field.key)] = field.csvCode()
+	}
+	f.Comment("FieldInfo describes a field of FormAnswer: the key it's stored under, its title in the form, the element it")
+	f.Comment("answers and the options it can be answered with, if any")
+	f.Type().Id("FieldInfo").Struct(
+		Id("Key").String(),
+		Id("Label").String(),
+		Id("Element").String(),
+		Id("Required").Bool(),
+		Id("Options").Index().String(),
+	)
+	f.Comment("Fields are the fields of FormAnswer, in the order of the form")
+	f.Var().Id("Fields").Op("=").Index().Id("FieldInfo").Custom(multiline, infos...)
+	f.Comment("Map returns the answer keyed by the keys of its fields, formatted like in CSVRecord, with sensitive answers redacted")
+	f.Func().Params(
+		Id("answer").Id("*FormAnswer"),
+	).Id("Map").Params().Map(String()).String().Block(
+		Id("answer").Op("=").Id("answer").Dot("Redacted").Call(),
+		Return(Map(String()).String().Values(mapped)),
+	)
+
+	// generate FormAnswer.Redacted(), for everything that stores or shows answers
+	var redactions []Code
+	for _, field := range fields {
+		switch {
+		case !field.sensitive:
+		case field.kind == "string":
+			redactions = append(redactions, If(Id("redacted").Dot(field.title).Op("!=").Lit("")).Block(
+				Id("redacted").Dot(field.title).Op("=").Lit("[redacted]"),
+			))
+		default:
+			// anything else is left unanswered
+			var zero Code
+			switch field.kind {
+			case "int", "int64", "float64":
+				zero = Lit(0)
+			case "bool":
+				zero = False()
+			case "time.Time":
+				zero = Qual("time", "Time").Values()
+			default:
+				zero = Nil()
+			}
+			redactions = append(redactions, Id("redacted").Dot(field.title).Op("=").Add(zero))
+		}
+	}
+	f.Comment("Redacted returns a copy of the answer with the answers to passwords and private fields replaced")
+	f.Func().Params(
+		Id("answer").Id("*FormAnswer"),
+	).Id("Redacted").Params().Op("*").Id("FormAnswer").Block(
+		append(append([]Code{Id("redacted").Op(":=").Op("*").Id("answer")}, redactions...), Return(Op("&").Id("redacted")))...,
+	)
+	if csvTime {
+		// leave unanswered dates empty, rather than writing out year 1
+		f.Func().Id("csvTime").Params(Id("t").Qual("time", "Time")).String().Block(
+			If(Id("t").Dot("IsZero").Call()).Block(Return(Lit(""))),
+			Return(Id("t").Dot("Format").Call(Qual("time", "RFC3339"))),
+		)
+	}
+	if csvJSON {
+		f.Func().Id("csvJSON").Params(Id("v").Interface()).String().Block(
+			List(Id("b"), Id("_")).Op(":=").Qual("encoding/json", "Marshal").Call(Id("v")),
+			Return(String().Call(Id("b"))),
+		)
+	}
+
+	// generate FormAnswer.Save()
+	f.Comment("Save appends the answer, along with the time it was submitted, as a line of json to the file at path, with sensitive answers redacted")
+	f.Func().Params(
+		Id("answer").Id("*FormAnswer"),
+	).Id("Save").Params(Id("path").String()).Error().Block(
+		List(Id("b"), Err()).Op(":=").Qual("encoding/json", "Marshal").Call(Struct(
+			Id("SubmittedAt").Qual("time", "Time").Tag(fieldTags("submitted-at", true)),
+			Id("FormAnswer"),
+		).Values(Qual("time", "Now").Call(), Op("*").Id("answer").Dot("Redacted").Call())),
+		If(Err().Op("!=").Nil()).Block(Return(Err())),
+		List(Id("file"), Err()).Op(":=").Qual("os", "OpenFile").Call(Id("path"), Qual("os", "O_APPEND").Op("|").Qual("os", "O_CREATE").Op("|").Qual("os", "O_WRONLY"), Op("0666")),
+		If(Err().Op("!=").Nil()).Block(Return(Err())),
+		Defer().Id("file").Dot("Close").Call(),
+		List(Id("_"), Err()).Op("=").Id("file").Dot("Write").Call(Append(Id("b"), LitRune('\n'))),
+		Return(Err()),
+	)
 
 	// generate ResponderData struct
 	f.Type().Id("ResponderData").Struct(Id("Data").String())
 
-	fmt.Printf("%#v", f)
+	// generate FieldError, returned by ParsePost() and Validate()
+	f.Comment("FieldError is a mistake in the answer to the field with Key, which the form is shown again with")
+	f.Type().Id("FieldError").Struct(
+		Id("Key").String(),
+		Id("Message").String(),
+	)
+	f.Func().Params(Id("err").Op("*").Id("FieldError")).Id("Error").Params().String().Block(
+		Return(Id("err").Dot("Key").Op("+").Lit(": ").Op("+").Id("err").Dot("Message")),
+	)
 
-	// make sure the package folder will exist
-	err = os.MkdirAll(formPackageName, 0777)
-	if err != nil {
-		fmt.Println("err mkdirall", err)
+	if !opts.quiet && opts.lang == "" {
+		fmt.Printf("%#v", f)
+	}
+
+	// the command written with --with-server imports the package, which has to be part of a module for that
+	var importPath string
+	if opts.withServer && opts.lang == "" {
+		if importPath, err = moduleImportPath(opts.outputDir); err != nil {
+			return 0, nil, fmt.Errorf("--with-server: %w", err)
+		}
+	}
+	// make sure the package folder will exist. there's no point in going on without it, nor after failing to write
+	// any of the files, which would leave a package that doesn't fit together
+	if err := opts.out.MkdirAll(opts.outputDir); err != nil {
+		return 0, nil, fmt.Errorf("creating --output %s: %w", opts.outputDir, err)
 	}
-	// write the generated form model to disk
-	generatedCode := fmt.Sprintf("%#v", f)
-	genCodeErr := os.WriteFile(filepath.Join(formPackageName, "generated-form-model.go"), []byte(generatedCode), 0777)
-	if genCodeErr != nil {
-		fmt.Println(genCodeErr)
+	// a translated template is all that's written for a language, the rest is shared with the default language
+	if opts.lang == "" {
+		// write the generated form model to disk
+		generatedCode := fmt.Sprintf("%#v", f)
+		if err := opts.out.WriteFile(filepath.Join(opts.outputDir, "generated-form-model.go"), []byte(generatedCode)); err != nil {
+			return 0, nil, err
+		}
+		written = append(written, "generated-form-model.go")
+		// write the generated form server to disk
+		server.langs = opts.langs
+		generatedCode = fmt.Sprintf("%#v", generateServer(opts.packageName, server))
+		if err := opts.out.WriteFile(filepath.Join(opts.outputDir, "generated-form-server.go"), []byte(generatedCode)); err != nil {
+			return 0, nil, err
+		}
+		written = append(written, "generated-form-server.go")
+		if opts.withServer {
+			cmdDir := filepath.Join(opts.outputDir, "cmd", opts.packageName)
+			if err := opts.out.MkdirAll(cmdDir); err != nil {
+				return 0, nil, fmt.Errorf("creating %s: %w", cmdDir, err)
+			}
+			command := fmt.Sprintf("%#v", generateCommand(opts.packageName, importPath))
+			if err := opts.out.WriteFile(filepath.Join(cmdDir, "main.go"), []byte(command)); err != nil {
+				return 0, nil, err
+			}
+			written = append(written, filepath.Join("cmd", opts.packageName, "main.go"))
+		}
 	}
 	var data TemplateData
-	data.Title = pageTitle
-	data.Content = template.HTML(strings.Join(htmlList, "\n"))
+	// the form server renders the form html as a template, so anything that looks like an action is escaped, before
+	// adding the actual actions
+	data.Title = escapeActions(pageTitle)
+	data.Lang = server.lang
+	if opts.lang != "" {
+		data.Lang = opts.lang
+	}
+	data.Content = template.HTML(templateActions(escapeActions(strings.Join(htmlList, "\n"))))
 
-	var styleData StyleData
 	if theme.background != "" {
 		styleData.Background = template.HTML(theme.background)
 	}
@@ -390,39 +4523,382 @@ func main() {
 	if theme.title != "" {
 		styleData.TitleColor = template.HTML(theme.title)
 	}
+	if theme.font != "" {
+		styleData.Font = template.CSS(theme.font)
+	}
+	if theme.headingFont != "" {
+		styleData.HeadingFont = template.CSS(theme.headingFont)
+	}
+	styleData.FontImports = theme.fontImports
+	if theme.backgroundDark != "" {
+		styleData.BackgroundDark = template.HTML(theme.backgroundDark)
+	}
+	if theme.bodyDark != "" {
+		styleData.BodyDark = template.HTML(theme.bodyDark)
+	}
+	if theme.titleDark != "" {
+		styleData.TitleColorDark = template.HTML(theme.titleDark)
+	}
 
+	// the response page's texts are filled in now, using different delimiters than the response itself, which is
+	// filled in by the form server. like the form, it's parsed as a template by the form server, so the texts are
+	// escaped the same way
+	responseData.Title = escapeActions(responseData.Title)
+	responseData.Message = template.HTML(escapeActions(string(responseData.Message)))
+	var responseBuf bytes.Buffer
+	if err := template.Must(template.New("").Delims("[[", "]]").Parse(responseTemplate)).Execute(&responseBuf, responseData); err != nil {
+		return 0, nil, err
+	}
+	response := responseBuf.String()
 	// stylesheet was passed with --stylesheet command: try to read it and then 
 	// *fully* replace the contents of stylesheetTemplate with the passed in style
-	if str, ok := readFileAsString(stylesheetFp); ok {
-		data.Stylesheet = template.CSS(str)
-		responseTemplate = strings.ReplaceAll(responseTemplate, "%SENTINEL%", fmt.Sprintf(`<style>%s</style>`, str))
+	if str, ok := readFileAsString(opts.stylesheetFp); ok {
+		// the honeypot has to stay hidden, whatever the stylesheet
+		if styleData.Honeypot {
+			str += "\n.honeypot { display: none; }\n"
+		}
+		data.Stylesheet = template.CSS(escapeActions(str))
+		response = strings.ReplaceAll(response, "%SENTINEL%", fmt.Sprintf(`<style>%s</style>`, escapeActions(str)))
 	} else {
 		// render the stylesheet 
 		t := template.Must(template.New("").Parse(stylesheetTemplate))
 		var styleBuf bytes.Buffer
-		t.Execute(&styleBuf, styleData)
-		data.Stylesheet = template.CSS(styleBuf.String())
-		responseTemplate = strings.ReplaceAll(responseTemplate, "%SENTINEL%", fmt.Sprintf(`<style>%s</style>`, styleBuf.String()))
+		if err := t.Execute(&styleBuf, styleData); err != nil {
+			return 0, nil, err
+		}
+		data.Stylesheet = template.CSS(escapeActions(styleBuf.String()))
+		response = strings.ReplaceAll(response, "%SENTINEL%", fmt.Sprintf(`<style>%s</style>`, escapeActions(styleBuf.String())))
+	}
+	if _, err := template.New("").Parse(response); err != nil {
+		return 0, nil, fmt.Errorf("the generated response page can't be rendered by the form server: %w", err)
 	}
 	// read any html header file that was declared
-	if str, ok := readFileAsString(headerFp); ok {
-		data.Header = template.HTML(str)
+	if str, ok := readFileAsString(opts.headerFp); ok {
+		data.Header = template.HTML(escapeActions(str))
 	}
 	// read any html footer file that was declared
-	if str, ok := readFileAsString(footerFp); ok {
-		data.Footer = template.HTML(str)
+	if str, ok := readFileAsString(opts.footerFp); ok {
+		data.Footer = template.HTML(escapeActions(str))
 	}
 
 	var buf bytes.Buffer
 	// write the page htmlList
 	t := template.Must(template.New("").Parse(htmlTemplate))
-	t.Execute(&buf, data)
-	indexWriteErr := os.WriteFile("index-template.html", buf.Bytes(), 0777)
-	if indexWriteErr != nil {
-		fmt.Println(indexWriteErr)
+	if err := t.Execute(&buf, data); err != nil {
+		return 0, nil, err
+	}
+	// the form server would only find out when serving the form
+	if _, err := template.New("").Parse(buf.String()); err != nil {
+		return 0, nil, fmt.Errorf("the generated form can't be rendered by the form server: %w", err)
+	}
+	// the templates are written next to the generated server, which embeds them
+	indexName := "index-template.html"
+	if opts.lang != "" {
+		indexName = fmt.Sprintf("index-template.%s.html", opts.lang)
+	}
+	if err := opts.out.WriteFile(filepath.Join(opts.outputDir, indexName), buf.Bytes()); err != nil {
+		return 0, nil, err
+	}
+	written = append(written, indexName)
+	if opts.lang != "" {
+		return len(fields), written, nil
+	}
+	if err := opts.out.WriteFile(filepath.Join(opts.outputDir, "response-template.html"), []byte(response)); err != nil {
+		return 0, nil, err
+	}
+	written = append(written, "response-template.html")
+	if opts.schema {
+		schema, err := jsonSchema(pageTitle, fields)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := opts.out.WriteFile(filepath.Join(opts.outputDir, "schema.json"), schema); err != nil {
+			return 0, nil, err
+		}
+		written = append(written, "schema.json")
+	}
+	if opts.openAPI {
+		if err := opts.out.WriteFile(filepath.Join(opts.outputDir, "openapi.yaml"), openAPI(pageTitle, fields, multipart, server)); err != nil {
+			return 0, nil, err
+		}
+		written = append(written, "openapi.yaml")
 	}
-	indexWriteErr = os.WriteFile("response-template.html", []byte(responseTemplate), 0777)
-	if indexWriteErr != nil {
-		fmt.Println(indexWriteErr)
+	for _, lang := range opts.langs {
+		langOpts := opts
+		langOpts.lang, langOpts.langs = lang, nil
+		_, translated, err := generate(langOpts)
+		if err != nil {
+			return 0, nil, fmt.Errorf("--lang %s: %w", lang, err)
+		}
+		written = append(written, translated...)
 	}
+	return len(fields), written, nil
+}
+
+// currencySymbols are shown in the label of a money element, for currencies that have one. other currencies are shown
+// by their code
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"USD": "$",
+	"GBP": "£",
+	"JPY": "¥",
+	"INR": "₹",
+	"KRW": "₩",
+	"PLN": "zł",
+	"SEK": "kr",
+	"NOK": "kr",
+	"DKK": "kr",
+}
+
+// countries are the ISO 3166 countries offered by the `country` element, as {code, english name} sorted by name
+var countries = [][2]string{
+	{"AF", "Afghanistan"},
+	{"AX", "Åland Islands"},
+	{"AL", "Albania"},
+	{"DZ", "Algeria"},
+	{"AD", "Andorra"},
+	{"AO", "Angola"},
+	{"AI", "Anguilla"},
+	{"AQ", "Antarctica"},
+	{"AG", "Antigua & Barbuda"},
+	{"AR", "Argentina"},
+	{"AM", "Armenia"},
+	{"AW", "Aruba"},
+	{"AU", "Australia"},
+	{"AT", "Austria"},
+	{"AZ", "Azerbaijan"},
+	{"BS", "Bahamas"},
+	{"BH", "Bahrain"},
+	{"BD", "Bangladesh"},
+	{"BB", "Barbados"},
+	{"BY", "Belarus"},
+	{"BE", "Belgium"},
+	{"BZ", "Belize"},
+	{"BJ", "Benin"},
+	{"BM", "Bermuda"},
+	{"BT", "Bhutan"},
+	{"BO", "Bolivia"},
+	{"BA", "Bosnia & Herzegovina"},
+	{"BW", "Botswana"},
+	{"BV", "Bouvet Island"},
+	{"BR", "Brazil"},
+	{"GB", "Britain (UK)"},
+	{"IO", "British Indian Ocean Territory"},
+	{"BN", "Brunei"},
+	{"BG", "Bulgaria"},
+	{"BF", "Burkina Faso"},
+	{"BI", "Burundi"},
+	{"KH", "Cambodia"},
+	{"CM", "Cameroon"},
+	{"CA", "Canada"},
+	{"CV", "Cape Verde"},
+	{"BQ", "Caribbean NL"},
+	{"KY", "Cayman Islands"},
+	{"CF", "Central African Rep."},
+	{"TD", "Chad"},
+	{"CL", "Chile"},
+	{"CN", "China"},
+	{"CX", "Christmas Island"},
+	{"CC", "Cocos (Keeling) Islands"},
+	{"CO", "Colombia"},
+	{"KM", "Comoros"},
+	{"CD", "Congo (Dem. Rep.)"},
+	{"CG", "Congo (Rep.)"},
+	{"CK", "Cook Islands"},
+	{"CR", "Costa Rica"},
+	{"CI", "Côte d'Ivoire"},
+	{"HR", "Croatia"},
+	{"CU", "Cuba"},
+	{"CW", "Curaçao"},
+	{"CY", "Cyprus"},
+	{"CZ", "Czech Republic"},
+	{"DK", "Denmark"},
+	{"DJ", "Djibouti"},
+	{"DM", "Dominica"},
+	{"DO", "Dominican Republic"},
+	{"TL", "East Timor"},
+	{"EC", "Ecuador"},
+	{"EG", "Egypt"},
+	{"SV", "El Salvador"},
+	{"GQ", "Equatorial Guinea"},
+	{"ER", "Eritrea"},
+	{"EE", "Estonia"},
+	{"SZ", "Eswatini (Swaziland)"},
+	{"ET", "Ethiopia"},
+	{"FK", "Falkland Islands"},
+	{"FO", "Faroe Islands"},
+	{"FJ", "Fiji"},
+	{"FI", "Finland"},
+	{"FR", "France"},
+	{"GF", "French Guiana"},
+	{"PF", "French Polynesia"},
+	{"TF", "French S. Terr."},
+	{"GA", "Gabon"},
+	{"GM", "Gambia"},
+	{"GE", "Georgia"},
+	{"DE", "Germany"},
+	{"GH", "Ghana"},
+	{"GI", "Gibraltar"},
+	{"GR", "Greece"},
+	{"GL", "Greenland"},
+	{"GD", "Grenada"},
+	{"GP", "Guadeloupe"},
+	{"GU", "Guam"},
+	{"GT", "Guatemala"},
+	{"GG", "Guernsey"},
+	{"GN", "Guinea"},
+	{"GW", "Guinea-Bissau"},
+	{"GY", "Guyana"},
+	{"HT", "Haiti"},
+	{"HM", "Heard Island & McDonald Islands"},
+	{"HN", "Honduras"},
+	{"HK", "Hong Kong"},
+	{"HU", "Hungary"},
+	{"IS", "Iceland"},
+	{"IN", "India"},
+	{"ID", "Indonesia"},
+	{"IR", "Iran"},
+	{"IQ", "Iraq"},
+	{"IE", "Ireland"},
+	{"IM", "Isle of Man"},
+	{"IL", "Israel"},
+	{"IT", "Italy"},
+	{"JM", "Jamaica"},
+	{"JP", "Japan"},
+	{"JE", "Jersey"},
+	{"JO", "Jordan"},
+	{"KZ", "Kazakhstan"},
+	{"KE", "Kenya"},
+	{"KI", "Kiribati"},
+	{"KP", "Korea (North)"},
+	{"KR", "Korea (South)"},
+	{"KW", "Kuwait"},
+	{"KG", "Kyrgyzstan"},
+	{"LA", "Laos"},
+	{"LV", "Latvia"},
+	{"LB", "Lebanon"},
+	{"LS", "Lesotho"},
+	{"LR", "Liberia"},
+	{"LY", "Libya"},
+	{"LI", "Liechtenstein"},
+	{"LT", "Lithuania"},
+	{"LU", "Luxembourg"},
+	{"MO", "Macau"},
+	{"MG", "Madagascar"},
+	{"MW", "Malawi"},
+	{"MY", "Malaysia"},
+	{"MV", "Maldives"},
+	{"ML", "Mali"},
+	{"MT", "Malta"},
+	{"MH", "Marshall Islands"},
+	{"MQ", "Martinique"},
+	{"MR", "Mauritania"},
+	{"MU", "Mauritius"},
+	{"YT", "Mayotte"},
+	{"MX", "Mexico"},
+	{"FM", "Micronesia"},
+	{"MD", "Moldova"},
+	{"MC", "Monaco"},
+	{"MN", "Mongolia"},
+	{"ME", "Montenegro"},
+	{"MS", "Montserrat"},
+	{"MA", "Morocco"},
+	{"MZ", "Mozambique"},
+	{"MM", "Myanmar (Burma)"},
+	{"NA", "Namibia"},
+	{"NR", "Nauru"},
+	{"NP", "Nepal"},
+	{"NL", "Netherlands"},
+	{"NC", "New Caledonia"},
+	{"NZ", "New Zealand"},
+	{"NI", "Nicaragua"},
+	{"NE", "Niger"},
+	{"NG", "Nigeria"},
+	{"NU", "Niue"},
+	{"NF", "Norfolk Island"},
+	{"MK", "North Macedonia"},
+	{"MP", "Northern Mariana Islands"},
+	{"NO", "Norway"},
+	{"OM", "Oman"},
+	{"PK", "Pakistan"},
+	{"PW", "Palau"},
+	{"PS", "Palestine"},
+	{"PA", "Panama"},
+	{"PG", "Papua New Guinea"},
+	{"PY", "Paraguay"},
+	{"PE", "Peru"},
+	{"PH", "Philippines"},
+	{"PN", "Pitcairn"},
+	{"PL", "Poland"},
+	{"PT", "Portugal"},
+	{"PR", "Puerto Rico"},
+	{"QA", "Qatar"},
+	{"RE", "Réunion"},
+	{"RO", "Romania"},
+	{"RU", "Russia"},
+	{"RW", "Rwanda"},
+	{"AS", "Samoa (American)"},
+	{"WS", "Samoa (western)"},
+	{"SM", "San Marino"},
+	{"ST", "Sao Tome & Principe"},
+	{"SA", "Saudi Arabia"},
+	{"SN", "Senegal"},
+	{"RS", "Serbia"},
+	{"SC", "Seychelles"},
+	{"SL", "Sierra Leone"},
+	{"SG", "Singapore"},
+	{"SK", "Slovakia"},
+	{"SI", "Slovenia"},
+	{"SB", "Solomon Islands"},
+	{"SO", "Somalia"},
+	{"ZA", "South Africa"},
+	{"GS", "South Georgia & the South Sandwich Islands"},
+	{"SS", "South Sudan"},
+	{"ES", "Spain"},
+	{"LK", "Sri Lanka"},
+	{"BL", "St Barthelemy"},
+	{"SH", "St Helena"},
+	{"KN", "St Kitts & Nevis"},
+	{"LC", "St Lucia"},
+	{"SX", "St Maarten (Dutch)"},
+	{"MF", "St Martin (French)"},
+	{"PM", "St Pierre & Miquelon"},
+	{"VC", "St Vincent"},
+	{"SD", "Sudan"},
+	{"SR", "Suriname"},
+	{"SJ", "Svalbard & Jan Mayen"},
+	{"SE", "Sweden"},
+	{"CH", "Switzerland"},
+	{"SY", "Syria"},
+	{"TW", "Taiwan"},
+	{"TJ", "Tajikistan"},
+	{"TZ", "Tanzania"},
+	{"TH", "Thailand"},
+	{"TG", "Togo"},
+	{"TK", "Tokelau"},
+	{"TO", "Tonga"},
+	{"TT", "Trinidad & Tobago"},
+	{"TN", "Tunisia"},
+	{"TR", "Turkey"},
+	{"TM", "Turkmenistan"},
+	{"TC", "Turks & Caicos Is"},
+	{"TV", "Tuvalu"},
+	{"UM", "US minor outlying islands"},
+	{"UG", "Uganda"},
+	{"UA", "Ukraine"},
+	{"AE", "United Arab Emirates"},
+	{"US", "United States"},
+	{"UY", "Uruguay"},
+	{"UZ", "Uzbekistan"},
+	{"VU", "Vanuatu"},
+	{"VA", "Vatican City"},
+	{"VE", "Venezuela"},
+	{"VN", "Vietnam"},
+	{"VG", "Virgin Islands (UK)"},
+	{"VI", "Virgin Islands (US)"},
+	{"WF", "Wallis & Futuna"},
+	{"EH", "Western Sahara"},
+	{"YE", "Yemen"},
+	{"ZM", "Zambia"},
+	{"ZW", "Zimbabwe"},
 }
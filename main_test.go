@@ -0,0 +1,1255 @@
+package main
+
+// server.go is a main package of its own, so the tests are run with the files they test:
+// go test main.go main_test.go
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// parsed parses format, failing the test on any mistake in it
+func parsed(t *testing.T, format string) []genValue {
+	t.Helper()
+	values, errs := parseFormat(format, "", true)
+	if len(errs) > 0 {
+		t.Fatalf("parsing %q: %v", format, parseErrors(errs))
+	}
+	return values
+}
+
+func TestComments(t *testing.T) {
+	values := parsed(t, "# a comment line\n"+
+		"  # an indented one\n"+
+		"form-title = Stickers # the title\n"+
+		"number[Moni]#amount = min=1\n"+
+		"form-bg = #fff\n")
+	if len(values) != 3 {
+		t.Fatalf("expected 3 elements, got %d: %+v", len(values), values)
+	}
+	if values[0].element != "form-title" || values[0].value != "Stickers" {
+		t.Errorf("the inline comment wasn't stripped: %+v", values[0])
+	}
+	if values[1].element != "number" || values[1].title != "Moni" || values[1].key != "amount" || values[1].value != "min=1" {
+		t.Errorf("#amount was taken for a comment rather than a key: %+v", values[1])
+	}
+	if values[2].value != "#fff" {
+		t.Errorf("a value starting with # was taken for a comment: %+v", values[2])
+	}
+}
+
+// memoryWriter keeps the generated files in memory, by file name
+type memoryWriter map[string][]byte
+
+func (memoryWriter) MkdirAll(dir string) error {
+	return nil
+}
+
+func (files memoryWriter) WriteFile(fp string, b []byte) error {
+	files[filepath.Base(fp)] = b
+	return nil
+}
+
+// generated generates the form package for format, failing the test when that doesn't work
+func generated(t *testing.T, format string) memoryWriter {
+	t.Helper()
+	files := memoryWriter{}
+	opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: files}
+	if _, _, err := generate(opts); err != nil {
+		t.Fatalf("generating %q: %v", format, err)
+	}
+	return files
+}
+
+// page stands in for the generated formPage, which the index template is executed with
+type page struct {
+	CSRFToken string
+	Values    url.Values
+	Errors    map[string]string
+}
+
+func (p page) Value(key string) string {
+	return p.Values.Get(key)
+}
+
+func (p page) Checked(key, value string) bool {
+	for _, v := range p.Values[key] {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (p page) Error(key string) string {
+	return p.Errors[key]
+}
+
+// rendered executes the generated index template the way the generated server does
+func rendered(t *testing.T, files memoryWriter, p page) string {
+	t.Helper()
+	tmpl, err := template.New("").Parse(string(files["index-template.html"]))
+	if err != nil {
+		t.Fatalf("parsing the index template: %v", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, p); err != nil {
+		t.Fatalf("executing the index template: %v", err)
+	}
+	return b.String()
+}
+
+// a tag of which every attribute is a name, optionally with a quoted value
+var (
+	wellFormedInput = regexp.MustCompile(`^<input(\s+[a-z-]+(="[^"<>]*")?)+\s*/>$`)
+	anyAttribute    = regexp.MustCompile(`\s([a-z-]+)(?:="([^"]*)")?`)
+)
+
+func TestEmailAttributes(t *testing.T) {
+	for format, expected := range map[string]map[string]string{
+		"email[Email address] =":                   {"value": "", "type": "email", "id": "email address", "name": "email address", "placeholder": "email@provider.tld"},
+		"!email[Contact] = you@example.com":        {"value": "", "type": "email", "id": "contact", "name": "contact", "placeholder": "you@example.com", "required": "", "aria-required": "true"},
+		`email[Work] = pattern=[^@]+@example\.com`: {"value": "", "type": "email", "id": "work", "name": "work", "placeholder": "email@provider.tld", "pattern": `[^@]+@example\.com`},
+		`email[Quoted] = "a, b" <a@b.c>`:           {"value": "", "type": "email", "id": "quoted", "name": "quoted", "placeholder": `"a, b" <a@b.c>`},
+	} {
+		index := rendered(t, generated(t, format), page{Values: url.Values{}})
+		tag := regexp.MustCompile(`<input [^\n]*type="email"[^\n]*/>`).FindString(index)
+		if !wellFormedInput.MatchString(tag) {
+			t.Errorf("%s: the email input is not well formed: %s", format, tag)
+			continue
+		}
+		attrs := map[string]string{}
+		for _, match := range anyAttribute.FindAllStringSubmatch(tag, -1) {
+			attrs[match[1]] = html.UnescapeString(match[2])
+		}
+		if len(attrs) != len(expected) {
+			t.Errorf("%s: expected the attributes %v, got %v", format, expected, attrs)
+		}
+		for name, value := range expected {
+			if got, ok := attrs[name]; !ok || got != value {
+				t.Errorf("%s: expected %s=%q, got %q in %s", format, name, value, got, tag)
+			}
+		}
+	}
+}
+
+func TestFormDescOverThreeLines(t *testing.T) {
+	values := parsed(t, "form-desc = Hey! \\\n"+
+		"    Hello! \\\n"+
+		"    This is a nonsensical form\n"+
+		"input[Name] = Your name\n")
+	if len(values) != 2 {
+		t.Fatalf("expected 2 elements, got %d: %+v", len(values), values)
+	}
+	if values[0].value != "Hey!\nHello!\nThis is a nonsensical form" {
+		t.Errorf("the continued lines weren't joined: %q", values[0].value)
+	}
+	if values[0].line != 1 || values[1].line != 4 {
+		t.Errorf("expected the elements on lines 1 and 4, got %d and %d", values[0].line, values[1].line)
+	}
+	index := string(generated(t, "form-desc = Hey! \\\n    Hello! \\\n    This is a nonsensical form\n")["index-template.html"])
+	if !strings.Contains(index, "Hey!<br>Hello!<br>This is a nonsensical form") {
+		t.Errorf("expected the description's lines to be broken with <br>:\n%s", index)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	for _, format := range []string{
+		"form-title = Words\ntextarea[Story]{minwords=5, maxwords=2} = Once upon a time",
+		"form-title = Words\ntextarea[Story]{minwords=none} = Once upon a time",
+		"form-title = Rating\nlikert[How was it?] = Venue; Food",
+	} {
+		opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: memoryWriter{}}
+		_, _, err := generate(opts)
+		if err == nil || !strings.HasPrefix(err.Error(), "line 2") {
+			t.Errorf("%q: expected an error on line 2, got %v", format, err)
+		}
+	}
+}
+
+// testGenerated generates the form package for format inside the module, where it can be built, and runs test
+// against it: the source of a test file in that package, without the package clause. the test file can post an
+// answer with post(values), e.g. post("gift=12,50")
+func testGenerated(t *testing.T, format, test string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building the generated package takes a while")
+	}
+	dir, err := os.MkdirTemp(".", "generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	packageName := filepath.Base(dir)
+	opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: dir, packageName: packageName, quiet: true, out: diskWriter{}}
+	if _, _, err := generate(opts); err != nil {
+		t.Fatalf("generating %q: %v", format, err)
+	}
+	post := `
+func post(values string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+`
+	src := "package " + packageName + "\n" + test + post
+	if err := os.WriteFile(filepath.Join(dir, "generated_test.go"), []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("go", "test", "./"+dir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("testing the package generated from %q: %v\n%s", format, err, out)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	testGenerated(t, "money[Gift] = min=1, max=500\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseCents(t *testing.T) {
+	for amount, expected := range map[string]int64{"12.50": 1250, "12,50": 1250, "12": 1200, "12.5": 1250, " 7,05 ": 705, ".5": 50} {
+		cents, err := parseCents(amount)
+		if err != nil || cents != expected {
+			t.Errorf("%q: expected %d cents, got %d, %v", amount, expected, cents, err)
+		}
+	}
+	for _, amount := range []string{"12.505", "12,505", "twelve", "-12", "1.2.3", "12,50,1"} {
+		if cents, err := parseCents(amount); err == nil {
+			t.Errorf("%q: expected an error, got %d cents", amount, cents)
+		}
+	}
+}
+
+func TestPostedMoney(t *testing.T) {
+	for values, expected := range map[string]int64{"gift=12.50": 1250, "gift=12%2C50": 1250, "gift=12": 1200} {
+		var answer FormAnswer
+		if err := answer.ParsePost(post(values)); err != nil || answer.Gift != expected {
+			t.Errorf("%s: expected %d cents, got %d, %v", values, expected, answer.Gift, err)
+		}
+	}
+	var answer FormAnswer
+	if err := answer.ParsePost(post("gift=12.505")); err == nil {
+		t.Errorf("more than two decimals were accepted: %d cents", answer.Gift)
+	}
+}
+`)
+}
+
+func TestParseFormat(t *testing.T) {
+	values := parsed(t, "form-title = Stickers\n"+
+		"# the shipping details\n"+
+		"\n"+
+		"input[Name] = Your name\n"+
+		"   # optional\n"+
+		"email[Email] = you@example.com\n")
+	if len(values) != 3 {
+		t.Fatalf("expected 3 elements, got %d: %+v", len(values), values)
+	}
+	for i, line := range []int{1, 4, 6} {
+		if values[i].line != line {
+			t.Errorf("expected %s on line %d, got %d", values[i].element, line, values[i].line)
+		}
+	}
+	for _, format := range []string{"", "\n\n  \n", "# nothing\n  # but comments\n"} {
+		if values := parsed(t, format); len(values) != 0 {
+			t.Errorf("%q: expected no elements, got %+v", format, values)
+		}
+	}
+	_, errs := parseFormat("form-title = Stickers\ninput[Name]\n", "", true)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 2") || !strings.Contains(errs[0].Error(), "missing '=' separator") {
+		t.Errorf("expected a missing '=' on line 2, got %v", errs)
+	}
+}
+
+func TestHead(t *testing.T) {
+	files := generated(t, "form-title = Stickers\ninput[Name] = Your name\n")
+	for _, name := range []string{"index-template.html", "response-template.html"} {
+		page := string(files[name])
+		head, _, found := strings.Cut(page, "</head>")
+		if !found {
+			t.Errorf("%s has no head:\n%s", name, page)
+			continue
+		}
+		for _, tag := range []string{`<meta charset="utf-8">`, `<meta name="viewport" content="width=device-width, initial-scale=1">`} {
+			if !strings.Contains(head, tag) {
+				t.Errorf("%s: %s is missing from the head:\n%s", name, tag, head)
+			}
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	index := rendered(t, generated(t, "input[Name] = Your name\n"+
+		"textarea[Your story] = Once upon a time\n"+
+		"email[Email address] = you@example.com\n"+
+		"number[Age] = min=18\n"), page{Values: url.Values{}})
+	labelFor := regexp.MustCompile(`<label for="([^"]*)">([^<]*)</label>`)
+	labels := map[string]string{}
+	for _, match := range labelFor.FindAllStringSubmatch(index, -1) {
+		labels[match[1]] = match[2]
+	}
+	for id, label := range map[string]string{"name": "Name", "your story": "Your story", "email address": "Email address", "age": "Age"} {
+		if labels[id] != label {
+			t.Errorf("expected a label %q for %q, got %q", label, id, labels[id])
+		}
+		if !strings.Contains(index, fmt.Sprintf(` id="%s"`, id)) {
+			t.Errorf("nothing has the id %q that the label %q is for", id, label)
+		}
+	}
+}
+
+func TestContinuedLines(t *testing.T) {
+	lines, lineNumbers := joinContinuedLines("form-title = Stickers\ninput[Name] = Your \\")
+	if len(lines) != 2 || lines[1] != "input[Name] = Your" || lineNumbers[1] != 2 {
+		t.Errorf("a continuation on the last line should end the line: %q %v", lines, lineNumbers)
+	}
+	values := parsed(t, "input[Name] = Your name \\")
+	if len(values) != 1 || values[0].value != "Your name" {
+		t.Errorf("a continuation on the last line should end the value: %+v", values)
+	}
+	values = parsed(t, "number[Age] = min=18, \\\n"+
+		"    max=99\n"+
+		"input[Sum] = 1 + 1 \\\n"+
+		"    = 2\n")
+	if len(values) != 2 {
+		t.Fatalf("expected 2 elements, got %d: %+v", len(values), values)
+	}
+	if values[0].value != "min=18, max=99" {
+		t.Errorf("the options weren't continued: %q", values[0].value)
+	}
+	if values[1].title != "Sum" || values[1].value != "1 + 1 = 2" || values[1].line != 3 {
+		t.Errorf("an = on a continued line was taken for the separator: %+v", values[1])
+	}
+}
+
+func TestResponsePageActions(t *testing.T) {
+	stylesheet := filepath.Join(t.TempDir(), "style.css")
+	if err := os.WriteFile(stylesheet, []byte("/* {{ not an action }} */ body { color: red; }"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	format := "response-title = Thanks {{ friend\nresponse-message = We got {{ .Data }}, see you {{ soon\ninput[Name] = Your name\n"
+	for _, stylesheetFp := range []string{"", stylesheet} {
+		files := memoryWriter{}
+		opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: files, stylesheetFp: stylesheetFp}
+		if _, _, err := generate(opts); err != nil {
+			t.Fatalf("generating %q: %v", format, err)
+		}
+		tmpl, err := template.New("").Parse(string(files["response-template.html"]))
+		if err != nil {
+			t.Fatalf("parsing the response template: %v", err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, struct{ Data string }{`{"name": "Ada"}`}); err != nil {
+			t.Fatalf("executing the response template: %v", err)
+		}
+		response := b.String()
+		for _, text := range []string{"<h1>Thanks {{ friend</h1>", "We got {{ .Data }}, see you {{ soon", "{&#34;name&#34;: &#34;Ada&#34;}"} {
+			if !strings.Contains(response, text) {
+				t.Errorf("%q is missing from the response page:\n%s", text, response)
+			}
+		}
+		if stylesheetFp != "" && !strings.Contains(response, "body { color: red; }") {
+			t.Errorf("the stylesheet is missing from the response page:\n%s", response)
+		}
+	}
+}
+
+func TestSplitOptions(t *testing.T) {
+	for _, test := range []struct {
+		content  string
+		sep      byte
+		expected []string
+	}{
+		{`1-10, 11-999, 1\,000 or more`, ',', []string{"1-10", "11-999", "1,000 or more"}},
+		{`a\=b, c=d`, ',', []string{"a=b", "c=d"}},
+		{`x\,y\,z`, ',', []string{"x,y,z"}},
+		{`Venue\; Food; Talks`, ';', []string{"Venue; Food", "Talks"}},
+		{`a, , b,`, ',', []string{"a", "b"}},
+		{`trailing \`, ',', []string{`trailing \`}},
+		{`C:\dir, d`, ',', []string{`C:\dir`, "d"}},
+	} {
+		options := splitOptions(test.content, test.sep)
+		if strings.Join(options, "|") != strings.Join(test.expected, "|") || len(options) != len(test.expected) {
+			t.Errorf("%q: expected %q, got %q", test.content, test.expected, options)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for content, expected := range map[string]string{
+		`1\,000`:      "1,000",
+		`a\=b`:        "a=b",
+		`\,\=\;\|`:    ",=;|",
+		`no escapes`:  "no escapes",
+		`C:\dir\file`: `C:\dir\file`,
+		`a\\,b`:       `a\,b`,
+	} {
+		if unescaped := unescape(content); unescaped != expected {
+			t.Errorf("%q: expected %q, got %q", content, expected, unescaped)
+		}
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for text, expected := range map[string]string{
+		"Name":               "Name",
+		"E-mail (work)":      "EMailWork",
+		"how was the event?": "HowWasTheEvent",
+		"2nd address":        "Field2ndAddress",
+		"42":                 "Field42",
+		"Rating 😀":           "Rating",
+		"😀 Mood 🎉":           "Mood",
+		"😀🎉":                 "",
+		"?!":                 "",
+		"":                   "",
+		"élan vital":         "ÉlanVital",
+		"straße":             "Straße",
+		"名前":                 "Field名前",
+		"snake_case":         "Snake_case",
+		"_hidden":            "Field_hidden",
+	} {
+		if ident := identifier(text); ident != expected {
+			t.Errorf("%q: expected %q, got %q", text, expected, ident)
+		}
+	}
+}
+
+// run the tests with -update to rewrite the golden files from what's generated now
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestIndexGolden(t *testing.T) {
+	format, err := os.ReadFile(filepath.Join("testdata", "golden.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := generated(t, string(format))["index-template.html"]
+	golden := filepath.Join("testdata", "golden-index.html")
+	if *update {
+		if err := os.WriteFile(golden, index, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(index, expected) {
+		t.Errorf("the generated index page differs from %s, run the tests with -update if that's intended:\n%s", golden, index)
+	}
+}
+
+func TestVariableReferences(t *testing.T) {
+	vars := map[string]string{"domain": "example.com", "sizes": "S, M, L"}
+	for _, test := range []struct {
+		element, value, expected string
+	}{
+		{"email", "you@domain", "you@domain"},
+		{"email", "pattern=.*@domain\\.com", "pattern=.*@domain\\.com"},
+		{"input", "pattern=[a-z]+@[a-z]+", "pattern=[a-z]+@[a-z]+"},
+		{"input", "@domain", "example.com"},
+		{"input", "see @domain for more", "see example.com for more"},
+		{"input", "(@domain)", "(example.com)"},
+		{"input", `"@domain"`, `"example.com"`},
+		{"input", "a,@domain", "a,@domain"},
+		{"radio", "@sizes, XL", `S\, M\, L, XL`},
+		{"form-title", "Sizes @sizes", "Sizes S, M, L"},
+	} {
+		v := genValue{element: test.element, value: test.value}
+		if err := substituteVariables(&v, vars); err != nil {
+			t.Errorf("%s = %s: %v", test.element, test.value, err)
+			continue
+		}
+		if v.value != test.expected {
+			t.Errorf("%s = %s: expected %q, got %q", test.element, test.value, test.expected, v.value)
+		}
+	}
+	values := parsed(t, "@domain = example.com\nemail[Work] = you@domain\ninput[Site] = @domain\n")
+	if len(values) != 2 || values[0].value != "you@domain" || values[1].value != "example.com" {
+		t.Errorf("only the @ at the start of a word should refer to the variable: %+v", values)
+	}
+	v := genValue{element: "input", value: "hi @nobody"}
+	if err := substituteVariables(&v, vars); err == nil || !strings.Contains(err.Error(), "@nobody") {
+		t.Errorf("expected @nobody to be undefined, got %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	testGenerated(t, "form-ratelimit = 5/minute\ninput[Name] = Your name\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllowResponse(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		if !allowResponse(post("name=a")) {
+			t.Fatalf("response %d was turned away", i+1)
+		}
+	}
+	if allowResponse(post("name=a")) {
+		t.Error("the 6th response was allowed")
+	}
+	other := post("name=b")
+	other.RemoteAddr = "192.0.2.99:1234"
+	if !allowResponse(other) {
+		t.Error("another client was turned away")
+	}
+}
+
+func TestHandlerStartsForgettingOnce(t *testing.T) {
+	Handler()
+	time.Sleep(10 * time.Millisecond)
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		Handler()
+	}
+	time.Sleep(10 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("calling Handler() again started %d goroutines", after-before)
+	}
+}
+`)
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	yaml := `title: Sticker order
+desc: Order some stickers, please
+response-title: Thanks!
+fields:
+  - type: input
+    label: Name
+    required: true
+    placeholder: First and last name
+    help: As printed on your ID
+  - type: radio
+    label: Size
+    options: [Small, Medium, Large]
+  - type: number
+    label: Stickers
+    key: amount
+    value: min=1, max=5
+  - type: textarea
+    label: Motivation
+    maxwords: 100
+  - type: repeat
+    label: Friend
+    value: 1..3
+    fields:
+      - type: email
+        label: Email
+`
+	format := `form-title = Sticker order
+form-desc = Order some stickers, please
+response-title = Thanks!
+!input[Name]{help=As printed on your ID} = First and last name
+radio[Size] = Small, Medium, Large
+number[Stickers]#amount = min=1, max=5
+textarea[Motivation]{maxwords=100} =
+repeat[Friend] = 1..3
+  email[Email] =
+end-repeat
+`
+	fromYAML := memoryWriter{}
+	opts := generateOptions{formatFp: "-", stdin: []byte(yaml), yaml: true, outputDir: "myform", packageName: "myform", quiet: true, out: fromYAML}
+	if _, _, err := generate(opts); err != nil {
+		t.Fatalf("generating from yaml: %v", err)
+	}
+	fromFormat := generated(t, format)
+	if len(fromYAML) != len(fromFormat) {
+		t.Fatalf("expected the same files, got %d and %d", len(fromYAML), len(fromFormat))
+	}
+	for name, b := range fromFormat {
+		if !bytes.Equal(fromYAML[name], b) {
+			t.Errorf("%s differs between the yaml and the format:\n%s\n\n%s", name, fromYAML[name], b)
+		}
+	}
+}
+
+func TestNumberOptions(t *testing.T) {
+	for content, expected := range map[string]string{
+		"min=1, max=5":        "",
+		"min=1,, max=5":       "",
+		"min=1, max=5,":       "",
+		"step=any":            "",
+		"min=-1.5, step=0.5":  "",
+		"min=1 max=5":         `option min=1 max=5 is not a number`,
+		"min":                 "option min has no value",
+		"min=1, max":          "option max has no value",
+		"showvalue":           `unknown option "showvalue"`,
+		"minimum=1":           `unknown option "minimum"`,
+		"=5":                  `unknown option ""`,
+		"min=abc":             "option min=abc is not a number",
+		"min==1":              "option min==1 is not a number",
+		"value=any":           "option value=any is not a number",
+		`min=1\,5`:            `option min=1,5 is not a number`,
+		"max=5, min=x, foo=1": `unknown option "foo"`,
+	} {
+		options, _ := parseOptions(content)
+		err := checkNumberOptions(options)
+		if expected == "" && err != nil {
+			t.Errorf("%q: unexpected error: %v", content, err)
+		} else if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+			t.Errorf("%q: expected an error with %q, got %v", content, expected, err)
+		}
+	}
+	for _, format := range []string{"number[Age] = min=1, maximum=5", "range[Volume] = min=0, max=ten"} {
+		opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: memoryWriter{}}
+		if _, _, err := generate(opts); err == nil {
+			t.Errorf("%q: expected an error", format)
+		}
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	lf, err := os.ReadFile(filepath.Join("testdata", "lf.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// saved with a byte order mark and windows line endings
+	crlf, err := os.ReadFile(filepath.Join("testdata", "crlf.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(crlf, []byte("\xef\xbb\xbf")) || !bytes.Contains(crlf, []byte("\r\n")) {
+		t.Fatal("testdata/crlf.txt lost its byte order mark or line endings")
+	}
+	fromLF, fromCRLF := parsed(t, string(lf)), parsed(t, string(crlf))
+	if fmt.Sprintf("%+v", fromLF) != fmt.Sprintf("%+v", fromCRLF) {
+		t.Errorf("the format parses differently with windows line endings:\n%+v\n%+v", fromLF, fromCRLF)
+	}
+	generatedLF, generatedCRLF := generated(t, string(lf)), generated(t, string(crlf))
+	for name, b := range generatedLF {
+		if !bytes.Equal(generatedCRLF[name], b) {
+			t.Errorf("%s differs with windows line endings:\n%s\n\n%s", name, generatedCRLF[name], b)
+		}
+	}
+}
+
+func TestHiddenRequiredField(t *testing.T) {
+	testGenerated(t, "radio[Attending as] = Private, Business\n"+
+		"!number[Employees]{if=attending-as=business} = min=1, max=10\n"+
+		"!input[Company]{if=attending-as=business} = Company name\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHidden(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("attending+as=private")); err != nil {
+		t.Fatalf("the hidden field was checked by ParsePost(): %v", err)
+	}
+	if errs := answer.Validate(); len(errs) > 0 {
+		t.Errorf("the hidden fields were checked by Validate(): %v", errs)
+	}
+}
+
+func TestShown(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("attending+as=business")); err != nil {
+		t.Fatal(err)
+	}
+	errs := answer.Validate()
+	if len(errs) != 2 || errs[0].Key != "employees" || errs[0].Message != "required" || errs[1].Key != "company" {
+		t.Errorf("expected employees and company to be required, got %v", errs)
+	}
+}
+`)
+}
+
+func TestTooLargeResponse(t *testing.T) {
+	testGenerated(t, "form-maxbody = 1kb\ninput[Name] = Your name\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTooLarge(t *testing.T) {
+	res := httptest.NewRecorder()
+	Handler().ServeHTTP(res, post("name="+strings.Repeat("a", 2000)))
+	if res.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected a 413, got %d: %s", res.Code, res.Body)
+	}
+}
+`)
+}
+
+func TestParseByteSize(t *testing.T) {
+	for size, expected := range map[string]int64{"500": 500, "100b": 100, "200kb": 200 << 10, "5mb": 5 << 20, " 2KB ": 2 << 10} {
+		if n, err := parseByteSize(size); err != nil || n != expected {
+			t.Errorf("%q: expected %d bytes, got %d, %v", size, expected, n, err)
+		}
+	}
+	for _, size := range []string{"", "kb", "5gb", "five mb", "1bb"} {
+		if n, err := parseByteSize(size); err == nil {
+			t.Errorf("%q: expected an error, got %d bytes", size, n)
+		}
+	}
+}
+
+func TestShowIfHiddenRequiredNumber(t *testing.T) {
+	testGenerated(t, "radio[Attending as] = Private, Business\n"+
+		"!number[Age]{show-if=attending-as==private} = min=18\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestShowIf(t *testing.T) {
+	for values, expected := range map[string]string{
+		"attending+as=business":        "",
+		"attending+as=private":         "age: required",
+		"attending+as=private&age=12":  "age: must be at least 18",
+		"attending+as=private&age=30":  "",
+		"attending+as=business&age=12": "",
+	} {
+		var answer FormAnswer
+		if err := answer.ParsePost(post(values)); err != nil {
+			t.Errorf("%s: %v", values, err)
+			continue
+		}
+		var got []string
+		for _, err := range answer.Validate() {
+			got = append(got, err.Error())
+		}
+		if strings.Join(got, "; ") != expected {
+			t.Errorf("%s: expected %q, got %q", values, expected, got)
+		}
+	}
+}
+`)
+}
+
+func TestEveryMistake(t *testing.T) {
+	testGenerated(t, "number[Employees] = min=1, max=10\n"+
+		"money[Gift] = min=1, max=5\n"+
+		"url[Site] =\n"+
+		"color[Favorite color] =\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOutOfRange(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("employees=50&gift=9")); err != nil {
+		t.Fatalf("ParsePost() stopped at a number it could read: %v", err)
+	}
+	errs := answer.Validate()
+	if len(errs) != 2 || errs[0].Error() != "employees: must be at most 10" || errs[1].Error() != "gift: must be at most 5" {
+		t.Errorf("expected both numbers to be out of range, got %v", errs)
+	}
+}
+
+func TestEveryKind(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("employees=0&gift=0.5&site=javascript:alert(1)&favorite+color=red")); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, err := range answer.Validate() {
+		keys = append(keys, err.Key)
+	}
+	if strings.Join(keys, ", ") != "employees, gift, site, favorite color" {
+		t.Errorf("expected a mistake in every field, got %v", keys)
+	}
+}
+`)
+}
+
+func TestAwkwardKeys(t *testing.T) {
+	for _, test := range []struct {
+		v        genValue
+		expected string
+	}{
+		{genValue{title: `Your "quote"`}, "your quote"},
+		{genValue{title: "Kid's size"}, "kids size"},
+		{genValue{title: "<b>Bold</b> & {{ brave }}"}, "bbold/b   brave "},
+		{genValue{title: "Name", key: `it's\`}, "its"},
+		{genValue{title: "E-mail (work)"}, "e-mail (work)"},
+		{genValue{title: "Name, first"}, "name first"},
+	} {
+		if key, _ := formatKeyAndTitle(test.v); key != test.expected {
+			t.Errorf("%+v: expected the key %q, got %q", test.v, test.expected, key)
+		}
+	}
+	format := "input[Your \"quote\"] = q\n" +
+		"!textarea[Kid's story] = s\n" +
+		"email[<b>Mail</b>] =\n" +
+		"number[Amount & more] = min=1\n" +
+		"radio[Kid's size] = S, M\n"
+	index := rendered(t, generated(t, format), page{Values: url.Values{}, Errors: map[string]string{"your quote": "wrong"}})
+	for _, tag := range regexp.MustCompile(`<(input|textarea|label)[^>]*>`).FindAllString(index, -1) {
+		if !regexp.MustCompile(`^<[a-z]+(\s+[a-z-]+(="[^"<>]*")?)*\s*/?>$`).MatchString(tag) {
+			t.Errorf("the tag isn't well formed: %s", tag)
+		}
+	}
+	if !strings.Contains(index, `<small class="field-error" id="your-quote-error">wrong</small>`) {
+		t.Errorf("the error of your quote isn't shown:\n%s", index)
+	}
+	testGenerated(t, format, `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("your+quote=q&kids+story=s&bmail%2Fb=a%40b.c&amount++more=2&kids+size=S")); err != nil {
+		t.Fatal(err)
+	}
+	if answer.YourQuote != "q" || answer.KidSStory != "s" || answer.BMailB != "a@b.c" || answer.AmountMore != 2 || answer.KidSSize != "S" {
+		t.Errorf("the answers weren't read under their keys: %+v", answer)
+	}
+}
+`)
+}
+
+func TestOtherOption(t *testing.T) {
+	format := "radio[Kid's size] = S, M, +other\nselect[Colour]#c = Red, +other\n"
+	index := rendered(t, generated(t, format), page{Values: url.Values{}})
+	for _, id := range []string{"kids size-other", "c-other"} {
+		script := fmt.Sprintf(`onchange="document.getElementById(&#39;%s&#39;).required = this.value == 'other'"`, id)
+		if !strings.Contains(index, script) {
+			t.Errorf("expected %s to be toggled with %s:\n%s", id, script, index)
+		}
+		if !strings.Contains(index, fmt.Sprintf(`id="%s"`, id)) {
+			t.Errorf("there's no %s to toggle", id)
+		}
+	}
+	if script := otherRequiredScript(`it's "here"`); strings.ContainsAny(script, `"`) || !strings.Contains(html.UnescapeString(script), `'it\'s \"here\"-other'`) {
+		t.Errorf("the key isn't escaped for js in an html attribute: %s", script)
+	}
+	testGenerated(t, format, `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOther(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("kids+size=other&kids+size-other=XXL&c=red&c-other=Teal")); err != nil {
+		t.Fatal(err)
+	}
+	if answer.KidSSize != "other" || answer.KidSSizeOther != "XXL" || answer.C != "red" || answer.COther != "" {
+		t.Errorf("only the picked other should be kept: %+v", answer)
+	}
+	if errs := answer.Validate(); len(errs) > 0 {
+		t.Errorf("unexpected mistakes: %v", errs)
+	}
+}
+`)
+}
+
+func TestExclusiveOption(t *testing.T) {
+	format := "checkboxes[Kid's allergies] = Nuts, \"Gluten\", ^I don't know\n"
+	index := rendered(t, generated(t, format), page{Values: url.Values{}})
+	for _, script := range []string{
+		`onchange="if (this.checked) document.getElementById(&#39;kids allergies-option-i don\&#39;t know&#39;).checked = false"`,
+		`onchange="if (this.checked) for (const box of document.getElementsByName(&#39;kids allergies&#39;)) box.checked = box === this"`,
+	} {
+		if !strings.Contains(index, script) {
+			t.Errorf("expected %s:\n%s", script, index)
+		}
+	}
+	if !strings.Contains(index, `id="kids allergies-option-i don&#39;t know"`) {
+		t.Errorf("there's no exclusive option to uncheck:\n%s", index)
+	}
+	testGenerated(t, format, `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExclusive(t *testing.T) {
+	for values, mistakes := range map[string]int{
+		"kids+allergies=i+don%27t+know":                                 0,
+		"kids+allergies=nuts&kids+allergies=%22gluten%22":               0,
+		"kids+allergies=nuts&kids+allergies=i+don%27t+know":             1,
+	} {
+		var answer FormAnswer
+		if err := answer.ParsePost(post(values)); err != nil {
+			t.Fatal(err)
+		}
+		if errs := answer.Validate(); len(errs) != mistakes {
+			t.Errorf("%s: expected %d mistakes, got %v", values, mistakes, errs)
+		}
+	}
+}
+`)
+}
+
+func TestFileUpload(t *testing.T) {
+	opts := generateOptions{formatFp: "-", stdin: []byte("form-title = Files\nfile[CV] = maxsize=big\n"), outputDir: "myform", packageName: "myform", quiet: true, out: memoryWriter{}}
+	if _, _, err := generate(opts); err == nil || !strings.HasPrefix(err.Error(), `line 2: file[CV]: invalid maxsize "big": `) {
+		t.Errorf("expected an invalid maxsize on line 2, with why, got %v", err)
+	}
+	testGenerated(t, "file[Kid's \"photo\"] = accept=.png, maxsize=1kb\n!file[CV] =\n", `
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func upload(t *testing.T, files map[string]string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for key, contents := range files {
+		part, err := w.CreateFormFile(key, "../"+key+".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(contents))
+	}
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestUpload(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(upload(t, map[string]string{"kids photo": "small", "cv": "resume"})); err != nil {
+		t.Fatal(err)
+	}
+	if errs := answer.Validate(); len(errs) > 0 {
+		t.Fatalf("unexpected mistakes: %v", errs)
+	}
+	dir := t.TempDir()
+	if err := answer.SaveUploads(dir); err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range map[string]string{"kids photo-kids photo.png": "small", "cv-cv.png": "resume"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != contents {
+			t.Errorf("%s: expected %q, got %q, %v", name, contents, b, err)
+		}
+	}
+}
+
+func TestUploadMistakes(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(upload(t, map[string]string{"kids photo": strings.Repeat("a", 2000)})); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, err := range answer.Validate() {
+		got = append(got, err.Error())
+	}
+	if strings.Join(got, "; ") != "kids photo: file is larger than 1kb; cv: required" {
+		t.Errorf("expected the photo to be too large and the cv to be missing, got %q", got)
+	}
+}
+`)
+}
+
+func TestExport(t *testing.T) {
+	testGenerated(t, "input[Name] = n\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNoPassword(t *testing.T) {
+	res := httptest.NewRecorder()
+	Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/export.csv", nil))
+	if res.Code != http.StatusNotFound {
+		t.Errorf("expected no export without a password, got %d: %s", res.Code, res.Body)
+	}
+}
+`)
+	testGenerated(t, "form-password = secret\ninput[Name, \"first\"] = n\ncheckboxes[Toppings] = Cheese, \"Olives\", Ham\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	handler := Handler()
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/export.csv", nil))
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("expected the export to need the password, got %d", res.Code)
+	}
+	var answer FormAnswer
+	if err := answer.ParsePost(post("name+first=Ada%2C+Countess&toppings=cheese&toppings=%22olives%22")); err != nil {
+		t.Fatal(err)
+	}
+	responses["a"] = map[string]interface{}{"name first": answer.NameFirst, "toppings": answer.Toppings}
+	req := httptest.NewRequest(http.MethodGet, "/export.csv", nil)
+	req.SetBasicAuth(BasicUser, "secret")
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if header, record := FormAnswerCSVHeader(), answer.CSVRecord(); strings.Join(header, "|") != "name first|toppings" || strings.Join(record, "|") != "Ada, Countess|cheese; \"olives\"" {
+		t.Errorf("expected the header and record to match, got %q and %q", header, record)
+	}
+	expected := "id,name first,toppings\na,\"Ada, Countess\",\"cheese; \"\"olives\"\"\"\n"
+	if res.Code != http.StatusOK || res.Body.String() != expected {
+		t.Errorf("expected the export %q, got %d: %q", expected, res.Code, res.Body)
+	}
+}
+`)
+}
+
+func TestRequiredYesNo(t *testing.T) {
+	testGenerated(t, "!yesno[Coming?] =\nyesno[Parking] = Ja, Nein\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestYesNo(t *testing.T) {
+	for values, expected := range map[string]string{
+		"":                        "coming?: required",
+		"parking=yes":             "coming?: required",
+		"coming%3F=no":            "",
+		"coming%3F=yes&parking=no": "",
+		"coming%3F=maybe":         "coming?: required",
+	} {
+		var answer FormAnswer
+		if err := answer.ParsePost(post(values)); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, err := range answer.Validate() {
+			got = append(got, err.Error())
+		}
+		if strings.Join(got, "; ") != expected {
+			t.Errorf("%q: expected %q, got %q", values, expected, got)
+		}
+	}
+	var answer FormAnswer
+	answer.ParsePost(post("coming%3F=yes&parking=no"))
+	if !answer.Coming || answer.Parking {
+		t.Errorf("expected yes and no, got %+v", answer)
+	}
+}
+`)
+}
+
+func TestPatternNames(t *testing.T) {
+	testGenerated(t, "input[Email]{pattern=[a-z]+@x\\.org} =\n"+
+		"email[Work] =\n"+
+		"input[Hex color]{pattern=#[0-9a-f]+} =\n"+
+		"color[C] =\n"+
+		"input[Ärger]{pattern=[a-z]+} =\n"+
+		"tel[Email pattern] = pattern=[0-9]+\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("email=a%40y.org&work=nope&hex+color=%23fff&c=red&%C3%A4rger=ABC&email+pattern=12")); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, err := range answer.Validate() {
+		keys = append(keys, err.Key)
+	}
+	if strings.Join(keys, ", ") != "email, work, c, ärger" {
+		t.Errorf("expected every field but the hex color and the phone number to be wrong, got %v", keys)
+	}
+}
+`)
+}
+
+func TestRepeatErrorPositions(t *testing.T) {
+	for format, position := range map[string]string{
+		"form-title = Guests\nrepeat[Guest] = 5..1\n  input[Name] =\nend-repeat\n":                  "line 2: repeat[Guest]: ",
+		"form-title = Guests\nrepeat[Guest] = 1..3\n  input[Name] =\n  number[Age] =\nend-repeat\n": "line 4: repeat[Guest]: number elements",
+	} {
+		opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: memoryWriter{}}
+		if _, _, err := generate(opts); err == nil || !strings.HasPrefix(err.Error(), position) {
+			t.Errorf("%q: expected an error starting with %q, got %v", format, position, err)
+		}
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	for format, position := range map[string]string{
+		"form-title = Week\nmatrix[Availability] = Mon, Tue\n":  "line 2: matrix[Availability]: expected rows and columns",
+		"form-title = Week\n\nmatrix[Availability] = Mon, | \n": "line 3: matrix[Availability]: needs at least one row",
+	} {
+		opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: memoryWriter{}}
+		if _, _, err := generate(opts); err == nil || !strings.HasPrefix(err.Error(), position) {
+			t.Errorf("%q: expected an error starting with %q, got %v", format, position, err)
+		}
+	}
+	testGenerated(t, "!matrix[Kid's availability] = Mon's, \"Tue\" | Morning, Late & evening\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("kids-availability-mon-s=morning&kids-availability-mon-s=late+%26+evening")); err != nil {
+		t.Fatal(err)
+	}
+	if got := answer.KidSAvailability["Mon's"]; len(got) != 2 || got[1] != "late & evening" {
+		t.Errorf("expected both of monday's boxes: %+v", answer.KidSAvailability)
+	}
+	if _, ok := answer.KidSAvailability["\"Tue\""]; ok {
+		t.Errorf("rows without checked boxes should be left out: %+v", answer.KidSAvailability)
+	}
+	if errs := answer.Validate(); len(errs) > 0 {
+		t.Errorf("unexpected mistakes: %v", errs)
+	}
+	var empty FormAnswer
+	if err := empty.ParsePost(post("")); err != nil {
+		t.Fatal(err)
+	}
+	if errs := empty.Validate(); len(errs) == 0 {
+		t.Errorf("a required matrix without checked boxes should be a mistake")
+	}
+}
+`)
+}
+
+func TestFormMethod(t *testing.T) {
+	for format, expected := range map[string]string{
+		"form-title = Search\nform-method = GET\ninput[Query] =\n":                                     "line 2: form-method: the form server only receives POST",
+		"form-action = https://example.com/f\n\nform-method = get\nfile[Photo's \"best\"] = image/*\n": "line 3: form-method: file uploads can't be sent with GET",
+	} {
+		opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: memoryWriter{}}
+		if _, _, err := generate(opts); err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%q: expected an error starting with %q, got %v", format, expected, err)
+		}
+	}
+	testGenerated(t, "form-action = https://example.com/f?id=\"1\"&x=<y>\nform-method = GET\ninput[Kid's \"query\"] =\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAction(t *testing.T) {
+	res := httptest.NewRecorder()
+	Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	form := "<form action=\"https://example.com/f?id=&#34;1&#34;&amp;x=&lt;y&gt;\" method=\"get\">"
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), form) {
+		t.Errorf("expected the form to be sent elsewhere with %s, got %d:\n%s", form, res.Code, res.Body)
+	}
+}
+`)
+}
+
+func TestEnumNames(t *testing.T) {
+	testGenerated(t, "radio[Field] = Error, Info, Warning\nselect[Form] = Answer, Content\nrepeat[Guest name] = 0..2\n  input[Name] =\nend-repeat\nradio[Guest] = Name, Age\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnums(t *testing.T) {
+	if len(ValidFields) != 3 || FieldWarning != "warning" || len(ValidForms) != 2 || GuestAge != "age" || len(ValidGuests) != 2 {
+		t.Errorf("expected every option to be valid: %v %v %v", ValidFields, ValidForms, ValidGuests)
+	}
+	var answer FormAnswer
+	if err := answer.ParsePost(post("field=error&form=answer&guest=name")); err != nil {
+		t.Fatal(err)
+	}
+	if errs := answer.Validate(); len(errs) > 0 {
+		t.Errorf("options without a constant should be valid too: %v", errs)
+	}
+}
+`)
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"contact.mould":    "input[Kid's \"email\"] = mail\n@include more/phone.mould # the nested one\n",
+		"more/phone.mould": "radio[Reach me by] = Phone, \"Mail\", Don't\n",
+		"loop.mould":       "input[Name] =\n\n@include loop.mould\n",
+	} {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0777)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for format, expected := range map[string]string{
+		"form-title = Sign-up\n@include\n":                      "line 2, column 1: @include needs the path",
+		"form-title = Sign-up\n@include " + dir + "/none.mould": "line 2: @include " + dir + "/none.mould: ",
+		"@include " + dir + "/loop.mould\n":                     filepath.Join(dir, "loop.mould") + ", line 3: @include loop.mould: the file includes itself",
+	} {
+		opts := generateOptions{formatFp: "-", stdin: []byte(format), outputDir: "myform", packageName: "myform", quiet: true, out: memoryWriter{}}
+		if _, _, err := generate(opts); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error with %q, got %v", format, expected, err)
+		}
+	}
+	testGenerated(t, "@shared = "+dir+"\nform-title = Sign-up\n@include @shared/contact.mould\ntextarea[Motivation] =\n", `
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIncluded(t *testing.T) {
+	var answer FormAnswer
+	if err := answer.ParsePost(post("kids+email=ada%40example.com&reach+me+by=don%27t&motivation=Cake")); err != nil {
+		t.Fatal(err)
+	}
+	if answer.KidSEmail != "ada@example.com" || answer.ReachMeBy != ReachMeByDonT || answer.Motivation != "Cake" {
+		t.Errorf("expected the included fields to be answered: %+v", answer)
+	}
+	if errs := answer.Validate(); len(errs) > 0 {
+		t.Errorf("unexpected mistakes: %v", errs)
+	}
+}
+`)
+}